@@ -0,0 +1,192 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+
+	"imgcomp/ui"
+	"imgcomp/util"
+
+	"fyne.io/fyne/v2/dialog"
+)
+
+// Decisions of this session, kept for undoing and written to the audit log.
+var actionLog *util.ActionLog
+
+// Deleted files are held here until the window is closed, unless they are moved to the trash.
+var holdingArea *util.HoldingArea
+
+var undoBar *ui.UndoBar
+
+// deleteFile moves the file to the trash or into the holding area, depending on the use-trash option.
+// It returns where the file went and a function restoring it.
+func deleteFile(path string) (string, func() error, error) {
+	if useTrash {
+		trashed, err := util.MoveFileToTrash(path)
+		if err != nil {
+			return "", nil, err
+		}
+		return trashed.FilesPath, trashed.Restore, nil
+	}
+	held, err := holdingArea.Hold(path)
+	if err != nil {
+		return "", nil, err
+	}
+	return held, func() error {
+		return holdingArea.Restore(held, path)
+	}, nil
+}
+
+// deleteImages deletes the given files and records the decision to keep the others.
+// The rule is the name of the rule that made the decision, empty for manual decisions.
+func deleteImages(deleted, kept []string, rule string) error {
+	var actions []util.Action
+	var undos []func() error
+	var errs []error
+	for _, path := range deleted {
+		destination, undo, err := deleteFile(path)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		action := util.Action{Kind: util.ActionDelete, Path: path, Destination: destination, Rule: rule}
+		if len(kept) == 1 {
+			action.OtherPath = kept[0]
+		}
+		actions = append(actions, action)
+		undos = append(undos, undo)
+	}
+	if len(actions) == 0 {
+		return errors.Join(errs...)
+	}
+
+	for _, path := range kept {
+		action := util.Action{Kind: util.ActionKeep, Path: path, Rule: rule}
+		if len(deleted) == 1 {
+			action.OtherPath = deleted[0]
+		}
+		actions = append(actions, action)
+	}
+	recordDecision(combineUndos(undos), actions...)
+	return errors.Join(errs...)
+}
+
+// replaceWithLink deletes the file and puts a link to the kept file in its place, so that references to its path keep working.
+func replaceWithLink(path, kept string) error {
+	destination, restore, err := deleteFile(path)
+	if err != nil {
+		return err
+	}
+	method, err := util.LinkFile(kept, path, linkMode)
+	if err != nil {
+		return errors.Join(err, restore())
+	}
+	fmt.Printf("Replaced %s with a %s of %s, the original was moved to %s\n", path, method, kept, destination)
+
+	recordDecision(func() error {
+		if err := os.Remove(path); err != nil {
+			return err
+		}
+		return restore()
+	},
+		util.Action{Kind: util.ActionReplace, Path: path, OtherPath: kept, Destination: destination, Method: method.String()},
+		util.Action{Kind: util.ActionKeep, Path: kept, OtherPath: path},
+	)
+	return nil
+}
+
+// ignorePair adds the pair to the ignore database and records the decision.
+func ignorePair(path1, path2 string) error {
+	if err := ignoreDB.Add(path1, path2); err != nil {
+		return err
+	}
+	recordDecision(func() error {
+		return ignoreDB.Remove(path1, path2)
+	}, util.Action{Kind: util.ActionIgnore, Path: path1, OtherPath: path2})
+	return nil
+}
+
+// combineUndos returns a function running all undo functions in reverse order,
+// or nil if any of them is nil.
+func combineUndos(undos []func() error) func() error {
+	for _, undo := range undos {
+		if undo == nil {
+			return nil
+		}
+	}
+	return func() error {
+		var errs []error
+		for i := len(undos) - 1; i >= 0; i-- {
+			if err := undos[i](); err != nil {
+				errs = append(errs, err)
+			}
+		}
+		return errors.Join(errs...)
+	}
+}
+
+// recordDecision adds a decision to the action log and updates the undo bar.
+// Failing to write the audit log does not block the review.
+func recordDecision(undo func() error, actions ...util.Action) {
+	if err := actionLog.Record(undo, actions...); err != nil {
+		fmt.Println("Error writing audit log:", err)
+	}
+	updateUndoBar()
+}
+
+// updateUndoBar shows the last decision of the session.
+func updateUndoBar() {
+	last := actionLog.Last()
+	status := "Nothing left to undo"
+	if len(last) > 0 {
+		status = "Last: " + last[0].String()
+		if undoable := actionLog.Undoable(); undoable == 0 {
+			status += " (cannot be undone)"
+		}
+	}
+	undoBar.SetState(actionLog.Undoable(), status)
+}
+
+// undoDecisions undoes the last n decisions and returns to the pair or group the earliest of them was made on.
+func undoDecisions(n int) {
+	undone, err := actionLog.Undo(n)
+	if err != nil {
+		dialog.ShowError(err, mainWindow)
+	}
+	updateUndoBar()
+	if len(undone) > 0 {
+		showDecision(undone[len(undone)-1])
+	}
+}
+
+// showDecision displays the pair or group the actions of a decision were made on.
+func showDecision(actions []util.Action) {
+	paths := make(map[string]bool)
+	for _, action := range actions {
+		paths[action.Path] = true
+		if action.OtherPath != "" {
+			paths[action.OtherPath] = true
+		}
+	}
+
+	switch {
+	case len(groupQueue) > 0:
+		for i, group := range groupQueue {
+			if slices.ContainsFunc(group, func(member util.CzkawkaImage) bool { return paths[member.Path] }) {
+				showQueuedGroup(i, 1)
+				return
+			}
+		}
+	case len(pairQueue) > 0:
+		for i, pair := range pairQueue {
+			if paths[pair.Image1.Path] && paths[pair.Image2.Path] {
+				showQueuedPair(i, 1)
+				return
+			}
+		}
+	case image1Path != "" && image2Path != "":
+		loadPair(image1Path, image2Path)
+	}
+}
@@ -0,0 +1,138 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"image"
+	"image/png"
+	"math"
+	"os"
+
+	"imgcomp/util"
+)
+
+// Exit codes of the headless diff mode.
+const (
+	exitIdentical = 0 // Images are within the threshold
+	exitDiffer    = 1 // Images differ beyond the threshold
+	exitError     = 2 // Images could not be loaded or the diff could not be written
+)
+
+// imageReport describes one of the compared files.
+type imageReport struct {
+	Path     string `json:"path"`
+	Width    int    `json:"width"`
+	Height   int    `json:"height"`
+	FileSize int64  `json:"file_size"`
+}
+
+// headlessReport is the JSON report printed by the headless diff mode.
+type headlessReport struct {
+	Image1           imageReport `json:"image1"`
+	Image2           imageReport `json:"image2"`
+	PixelCount       uint64      `json:"pixel_count"`
+	MAE              float64     `json:"mae"`
+	ChannelMAE       [3]float64  `json:"channel_mae"`
+	AlphaMAE         float64     `json:"alpha_mae"`
+	RMSE             float64     `json:"rmse"`
+	PSNR             *float64    `json:"psnr,omitempty"` // Omitted for identical images, where it is infinite
+	MaxError         uint32      `json:"max_error"`
+	DiffThreshold    uint32      `json:"diff_threshold"`
+	DifferingPixels  uint64      `json:"differing_pixels"`
+	DifferingPercent float64     `json:"differing_percent"`
+	Notes            []string    `json:"notes,omitempty"`
+	DiffImage        string      `json:"diff_image,omitempty"`
+	MaxMAE           float64     `json:"max_mae"`
+	Differs          bool        `json:"differs"`
+}
+
+// loadImageReport loads an image at full resolution and describes the file.
+func loadImageReport(path string) (image.Image, imageReport, error) {
+	img, err := util.LoadImage(path)
+	if err != nil {
+		return nil, imageReport{}, err
+	}
+	fileInfo, err := os.Stat(path)
+	if err != nil {
+		return nil, imageReport{}, err
+	}
+	return img, imageReport{
+		Path:     path,
+		Width:    img.Bounds().Dx(),
+		Height:   img.Bounds().Dy(),
+		FileSize: fileInfo.Size(),
+	}, nil
+}
+
+// writePNG encodes the image as PNG to the given path.
+func writePNG(path string, img image.Image) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := png.Encode(file, img); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// runHeadless compares two images at full resolution without opening a window.
+// It optionally writes the diff image, prints a JSON report to stdout
+// and returns the process exit code: whether the MAE exceeds maxMAE, or an error.
+func runHeadless(path1, path2, diffOutput string, maxMAE float64) int {
+	img1, report1, err := loadImageReport(path1)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Error loading image 1:", err)
+		return exitError
+	}
+	img2, report2, err := loadImageReport(path2)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Error loading image 2:", err)
+		return exitError
+	}
+
+	aligned2, alignment, notes := alignPair(img1, img2)
+	compared1, compared2 := alignment.Crop(img1, aligned2)
+	diff, stats := util.ComputeImageDiffFast(&compared1, &compared2, scalingAlgo, false, diffThreshold)
+
+	if diffOutput != "" {
+		if err := writePNG(diffOutput, diff); err != nil {
+			fmt.Fprintln(os.Stderr, "Error writing diff image:", err)
+			return exitError
+		}
+	}
+
+	report := headlessReport{
+		Image1:           report1,
+		Image2:           report2,
+		PixelCount:       stats.PixelCount,
+		MAE:              stats.MAE,
+		ChannelMAE:       stats.ChannelMAE,
+		AlphaMAE:         stats.AlphaMAE,
+		RMSE:             stats.RMSE,
+		MaxError:         stats.MaxError,
+		DiffThreshold:    stats.Threshold,
+		DifferingPixels:  stats.DifferingPixels,
+		DifferingPercent: stats.DifferingPercent(),
+		Notes:            notes,
+		DiffImage:        diffOutput,
+		MaxMAE:           maxMAE,
+		Differs:          stats.MAE > maxMAE,
+	}
+	if !math.IsInf(stats.PSNR, 1) {
+		report.PSNR = &stats.PSNR
+	}
+
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(report); err != nil {
+		fmt.Fprintln(os.Stderr, "Error writing report:", err)
+		return exitError
+	}
+
+	if report.Differs {
+		return exitDiffer
+	}
+	return exitIdentical
+}
@@ -6,6 +6,8 @@ import (
 	"image"
 	"os"
 	"os/exec"
+	"path/filepath"
+	"strings"
 	"sync"
 	"time"
 
@@ -16,12 +18,15 @@ import (
 	"fyne.io/fyne/v2/app"
 	"fyne.io/fyne/v2/container"
 	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/driver/desktop"
 )
 
 var pixelWiseTab *ui.PixelWiseTab
 var layerSliderTab *ui.LayerSliderTab
+var blinkTab *ui.BlinkTab
 
 var comparisonPanel *ui.ImageComparisonPanel
+var loupe *ui.Loupe
 
 var image1Path string
 var image2Path string
@@ -29,46 +34,187 @@ var image2Path string
 var image1 *image.Image
 var image2 *image.Image
 
+// Full resolution versions of the selected frame, which the pixel statistics are computed on.
+var original1 image.Image
+var original2 image.Image
+
+// How original1 and original2 were aligned for the comparison, used to find the same spot on both.
+// Hover callbacks read it while a pair is rendered, so it is only accessed with pairAlignmentMutex held.
+var pairAlignment util.Alignment
+var pairAlignmentMutex sync.Mutex
+
+// All decoded frames of both images, image1 and image2 hold rescaled copies of the selected frame.
+var animation1 *util.Animation
+var animation2 *util.Animation
+
+// Frame scrubbers of the Difference and Layer Slider tabs, kept on the same frame.
+var frameScrubbers []*ui.FrameScrubber
+
 var scalingAlgo util.ScalingAlgorithm
 
+// Per-channel difference above which a pixel is counted as differing.
+var diffThreshold uint32
+
+// Whether rotated or mirrored copies are aligned before comparing.
+var detectTransform bool
+
+// Whether pairs where one image is a crop of the other are compared on their common region only.
+var detectCrop bool
+
+// Side length of the perceptual hashes shown in the comparison panel.
+var hashSize int
+
+// Whether deleted files are moved to the trash instead of being removed.
+var useTrash bool
+
+// How the Replace with other button points the replaced file at the kept one.
+var linkMode util.LinkMode
+
 // Reference to the main window, used for displaying dialogs and other UI elements.
 var mainWindow fyne.Window
 
 // WaitGroup to synchronize loading of images
 var loadingWaitGroup = &sync.WaitGroup{}
 
+// alignPair turns img2 back into the orientation of img1 if it is a rotated or mirrored copy,
+// and narrows the comparison down to the common region if one is a crop of the other.
+// It returns img2 in the orientation of img1, the alignment and notes describing what was done.
+func alignPair(img1, img2 image.Image) (image.Image, util.Alignment, []string) {
+	var notes []string
+	transform := util.Identity
+	if detectTransform {
+		transform, img2 = util.DetectTransform(img1, img2)
+		if transform != util.Identity {
+			notes = append(notes, fmt.Sprintf("Image 2 is image 1 %s, comparing against the aligned image 2.", transform))
+		}
+	}
+	alignment := util.NewAlignment(img1, img2, transform.Inverse())
+	if detectCrop {
+		if overlap, ok := util.FindOverlap(img1, img2); ok {
+			rect, bounds := overlap.Rect1, img1.Bounds()
+			if overlap.CroppedImage == 1 {
+				rect, bounds = overlap.Rect2, img2.Bounds()
+			}
+			notes = append(notes, fmt.Sprintf(
+				"Image %d is a crop of image %d covering %.0f%% x %.0f%% at %s, comparing only the overlapping region.",
+				overlap.CroppedImage, 3-overlap.CroppedImage,
+				float64(rect.Dx())/float64(bounds.Dx())*100,
+				float64(rect.Dy())/float64(bounds.Dy())*100,
+				rect,
+			))
+			alignment.Rect1, alignment.Rect2 = overlap.Rect1, overlap.Rect2
+		}
+	}
+	return img2, alignment, notes
+}
+
 func renderComparison() {
+	aligned2, alignment, notes := alignPair(original1, original2)
+	compared1, compared2 := alignment.Crop(original1, aligned2)
+	pixelWiseTab.SetNote(strings.Join(notes, "\n"))
+	// The loupe shows the same spot of both images as they are compared
+	pairAlignmentMutex.Lock()
+	pairAlignment = alignment
+	pairAlignmentMutex.Unlock()
+	comparisonPanel.SetAlignment(alignment)
+	loupe.SetImage(0, original1, alignment.Rect1)
+	loupe.SetImage(1, aligned2, alignment.Rect2)
+
 	startTime := time.Now()
-	diff, mae, pixelCount := util.ComputeImageDiffFast(image1, image2, scalingAlgo, pixelWiseTab.ShowMonochrome())
+	diff, stats := util.ComputeImageDiffFast(&compared1, &compared2, scalingAlgo, pixelWiseTab.ShowMonochrome(), diffThreshold)
 	fmt.Printf("Image difference computed in %v\n", time.Since(startTime))
 
-	pixelWiseTab.SetImage(&diff)
+	// Structural similarity compares structure at viewing scale, so it runs on the previews;
+	// its floating point maps would not fit in memory for large photos anyway.
+	startTime = time.Now()
+	preview1 := util.RescaleImageFast(compared1, scalingAlgo)
+	preview2 := util.RescaleImageFast(compared2, scalingAlgo)
+	ssim := util.ComputeSSIM(&preview1, &preview2, scalingAlgo)
+	msssim := util.ComputeMSSSIM(&preview1, &preview2, scalingAlgo)
+	fmt.Printf("Structural similarity computed in %v\n", time.Since(startTime))
+
+	if pixelWiseTab.ShowSSIMMap() {
+		ssimMap := util.SSIMMapImage(ssim)
+		pixelWiseTab.SetImage(&ssimMap)
+		loupe.SetImage(2, ssimMap, ssimMap.Bounds())
+	} else {
+		pixelWiseTab.SetImage(&diff)
+		loupe.SetImage(2, diff, diff.Bounds())
+	}
 
 	// Update the comparison section with the new images
 	layerSliderTab.RemoveAll()
-	if mae > 0 {
-		layerSliderTab.Compare(image1, image2, scalingAlgo)
+	if !stats.Identical() {
+		layerSliderTab.Compare(&compared1, &compared2, scalingAlgo)
 	}
 
 	layerSliderTab.Refresh()
+	blinkTab.Compare(&compared1, &compared2, scalingAlgo)
 
-	if mae == 0 {
-		pixelWiseTab.SetMessage("Images are identical")
-	} else {
-		pixelWiseTab.SetMessage(fmt.Sprintf("Images differ with MAE: %.2f (%d px)", mae, pixelCount))
+	pixelWiseTab.SetStats(stats, ssim.Score, msssim)
+
+	comparisonPanel.SetHashDistances(util.CompareHashes(*image1, *image2, hashSize))
+}
+
+// preparePair runs the comparisons that only depend on the loaded files, not on the selected frame.
+// It computes the per-frame comparison of both images, resets the frame scrubbers,
+// suggests which image to keep and warns about mismatching EXIF orientation.
+func preparePair() {
+	if animation1 == nil || animation2 == nil {
+		return
+	}
+	comparison := util.CompareAnimations(animation1, animation2, scalingAlgo)
+	selectFrame(0)
+	for _, scrubber := range frameScrubbers {
+		scrubber.SetComparison(comparison)
+	}
+
+	suggestion := util.SuggestKeeper(
+		util.AnalyzeQuality(image1Path, animation1.Frame(0)),
+		util.AnalyzeQuality(image2Path, animation2.Frame(0)),
+	)
+	comparisonPanel.SetSuggestion(suggestion.Keep, suggestion.Summary())
+
+	orientation1 := util.ReadOrientation(image1Path)
+	orientation2 := util.ReadOrientation(image2Path)
+	if orientation1 == orientation2 {
+		comparisonPanel.SetOrientationWarning("")
+		return
+	}
+	applied := "Orientation was applied before comparing, so identical pixel data compares as identical."
+	if !util.ApplyOrientation {
+		applied = "Orientation is not applied, so rotated copies will show large differences."
 	}
+	comparisonPanel.SetOrientationWarning(fmt.Sprintf(
+		"EXIF orientation differs: image 1 is %d (%s), image 2 is %d (%s). %s",
+		orientation1, util.OrientationName(orientation1),
+		orientation2, util.OrientationName(orientation2),
+		applied,
+	))
 }
 
-func loadAndRenderImage(path string, index int, wg bool) {
-	// Load the image from the specified path
-	img, err := util.LoadImage(path)
+// selectFrame switches image1 and image2 to the given frame of their animations.
+func selectFrame(frame int) {
+	rescaled1 := util.RescaleImageFast(animation1.Frame(frame), scalingAlgo)
+	rescaled2 := util.RescaleImageFast(animation2.Frame(frame), scalingAlgo)
+	image1 = &rescaled1
+	image2 = &rescaled2
+	original1 = animation1.Frame(frame)
+	original2 = animation2.Frame(frame)
+}
+
+// loadAndRenderImage loads the image at path into the given slot and reports whether it loaded.
+// Outside a wait group the comparison is rendered right away once both slots hold an image.
+func loadAndRenderImage(path string, index int, wg bool) bool {
+	// Load the image and all of its animation frames from the specified path
+	anim, err := util.LoadAnimation(path)
 	if err != nil {
 		fmt.Println("Error loading image:", err)
 		if wg {
 			loadingWaitGroup.Done()
 		}
 		dialog.ShowError(err, mainWindow) // Show error dialog if image loading fails
-		return
+		return false
 	}
 
 	// Determine the file info to get the size and other details
@@ -78,20 +224,25 @@ func loadAndRenderImage(path string, index int, wg bool) {
 			loadingWaitGroup.Done()
 		}
 		dialog.ShowError(err, mainWindow)
-		return
+		return false
 	}
 
 	// Determine which image to update based on the index
+	img := anim.Frame(0)
 	rescaledImg := util.RescaleImageFast(img, scalingAlgo)
 	if index == 0 {
 		image1Path = path
 		image1 = &rescaledImg
+		original1 = img
+		animation1 = anim
 		fyne.Do(func() {
 			comparisonPanel.SetImage(1, &img, path, fileInfo.Size())
 		})
 	} else {
 		image2Path = path
 		image2 = &rescaledImg
+		original2 = img
+		animation2 = anim
 		fyne.Do(func() {
 			comparisonPanel.SetImage(2, &img, path, fileInfo.Size())
 		})
@@ -102,11 +253,13 @@ func loadAndRenderImage(path string, index int, wg bool) {
 	// we need to indicate that we're done loading this image.
 	if wg {
 		loadingWaitGroup.Done()
-	} else {
+	} else if original1 != nil && original2 != nil {
 		// otherwise, we can directly render the comparison
 		// as we're running in the main thread and have both images loaded.
+		preparePair()
 		renderComparison()
 	}
+	return true
 }
 
 func main() {
@@ -115,16 +268,45 @@ func main() {
 	image2Flag := flag.String("image2", "", "Path to the second image")
 	showManagementButtonsFlag := flag.Bool("show-management-buttons", true, "Show image management buttons (delete, ignore)")
 	scalingAlgoFlag := flag.String("scaling-algo", "bilinear", "Image scaling algorithm (bilinear, nearest)")
-	useTrashFlag := flag.Bool("use-trash", false, "Use system trash for deletions")
+	linkModeFlag := flag.String("link-mode", util.Hardlink.String(), "How Replace with other links the replaced file to the kept one (hardlink, symlink, reflink, copy)")
+	useTrashFlag := flag.Bool("use-trash", false, "Move deleted files to the freedesktop.org trash instead of removing them when the window is closed, or restoring them at the next start after a crash")
+	diffThresholdFlag := flag.Uint("diff-threshold", util.DefaultDiffThreshold, "Per-channel difference (0-255) above which a pixel counts as differing")
+	jxlDecoderFlag := flag.String("jxl-decoder", util.JXLDecoderCommand, "External JPEG XL decoder, invoked as <command> <input> <output.png>; JPEG XL files need it since there is no built-in decoder, empty to disable")
+	applyOrientationFlag := flag.Bool("apply-orientation", true, "Rotate images according to their EXIF orientation tag")
+	detectTransformFlag := flag.Bool("detect-transform", true, "Detect when image 2 is a rotated or mirrored copy of image 1 and compare against the aligned version")
+	detectCropFlag := flag.Bool("detect-crop", true, "Detect when one image is a crop of the other and compare only the overlapping region")
+	hashSizeFlag := flag.Int("hash-size", util.DefaultHashSize, fmt.Sprintf("Side length of perceptual hashes, producing hash-size squared bits (2-%d)", util.MaxHashSize))
+	czkawkaFlag := flag.String("czkawka", "", "Path to a Czkawka similar images JSON results file to review pair by pair")
+	sameDimensionsOnlyFlag := flag.Bool("same-dimensions-only", true, "Only queue Czkawka pairs whose images have the same dimensions")
+	groupModeFlag := flag.Bool("group-mode", false, "Review Czkawka results group by group instead of pair by pair")
+	rulesFlag := flag.String("rules", "", "Path to a JSON rule file resolving Czkawka pairs automatically, applied after confirming a dry-run report")
+	rulesDryRunFlag := flag.Bool("rules-dry-run", false, "Print what the rules would delete from the Czkawka pairs and exit")
+	ignoreDBFlag := flag.String("ignore-db", "", "Path to the database of ignored pairs, defaults to imgcomp/ignored_pairs.json in the XDG data directory")
+	auditLogFlag := flag.String("audit-log", "", "Path to append keep, delete and ignore decisions to, defaults to imgcomp/audit.log in the XDG data directory")
+	importIgnoreListFlag := flag.String("import-ignore-list", "", "Import pairs from a legacy ignored_images.txt file into the ignore database")
+	pruneIgnoredFlag := flag.Bool("prune-ignored", false, "Forget ignored pairs with a file that is not in the -czkawka results, which must then cover every scanned directory")
+	headlessFlag := flag.Bool("headless", false, "Compare image1 and image2 without opening a window, print a JSON report and exit with 1 if they differ")
+	diffOutputFlag := flag.String("diff-output", "", "Path to write the diff PNG to in headless mode")
+	maxMAEFlag := flag.Float64("max-mae", 0, "Largest MAE at which images still count as identical in headless mode")
 	flag.Parse()
 
-	if *useTrashFlag {
-		// Check if 'trash' command is available
-		_, err := exec.LookPath("trash")
-		if err != nil {
-			fmt.Println("Error: 'trash' command not found. Please install it or disable the use-trash option.")
-			return
-		}
+	diffThreshold = uint32(*diffThresholdFlag)
+	util.JXLDecoderCommand = *jxlDecoderFlag
+	util.ApplyOrientation = *applyOrientationFlag
+	detectTransform = *detectTransformFlag
+	detectCrop = *detectCropFlag
+	useTrash = *useTrashFlag
+
+	if err := util.CheckHashSize(*hashSizeFlag); err != nil {
+		fmt.Println("Error:", err)
+		return
+	}
+	hashSize = *hashSizeFlag
+
+	var err error
+	if linkMode, err = util.ParseLinkMode(*linkModeFlag); err != nil {
+		fmt.Println("Error:", err)
+		return
 	}
 
 	switch *scalingAlgoFlag {
@@ -134,6 +316,80 @@ func main() {
 		scalingAlgo = util.Bilinear
 	}
 
+	if *headlessFlag {
+		if *image1Flag == "" || *image2Flag == "" {
+			fmt.Fprintln(os.Stderr, "Error: headless mode requires both -image1 and -image2.")
+			os.Exit(exitError)
+		}
+		os.Exit(runHeadless(*image1Flag, *image2Flag, *diffOutputFlag, *maxMAEFlag))
+	}
+
+	ignoreDBPath := *ignoreDBFlag
+	if ignoreDBPath == "" {
+		var err error
+		if ignoreDBPath, err = util.DefaultIgnoreDBPath(); err != nil {
+			fmt.Println("Error locating the ignore database:", err)
+			return
+		}
+	}
+	if err := openIgnoreDB(ignoreDBPath, *importIgnoreListFlag); err != nil {
+		fmt.Println("Error opening the ignore database:", err)
+		return
+	}
+
+	if *pruneIgnoredFlag && *czkawkaFlag == "" {
+		fmt.Println("Error: -prune-ignored needs -czkawka results to know which files still exist.")
+		return
+	}
+
+	var rules *util.RuleSet
+	if *rulesFlag != "" {
+		if *czkawkaFlag == "" || *groupModeFlag {
+			fmt.Println("Error: rules resolve the pair queue and need -czkawka without -group-mode.")
+			return
+		}
+		if rules, err = util.LoadRuleSet(*rulesFlag); err != nil {
+			fmt.Println("Error reading rules:", err)
+			return
+		}
+	}
+	if *rulesDryRunFlag {
+		if rules == nil {
+			fmt.Println("Error: -rules-dry-run needs -rules.")
+			return
+		}
+		if err := buildQueue(*czkawkaFlag, *sameDimensionsOnlyFlag, false, false); err != nil {
+			fmt.Println("Error reading Czkawka results:", err)
+			return
+		}
+		fmt.Print(util.ResolutionReport(resolveQueue(rules), len(pairQueue)))
+		return
+	}
+
+	auditLogPath := *auditLogFlag
+	if auditLogPath == "" {
+		var err error
+		if auditLogPath, err = util.DefaultAuditLogPath(); err != nil {
+			fmt.Println("Error locating the audit log:", err)
+			return
+		}
+	}
+	actionLog = util.NewActionLog(auditLogPath)
+	dataDir, err := util.DataDir()
+	if err != nil {
+		fmt.Println("Error locating the data directory:", err)
+		return
+	}
+	// Deletions of sessions that crashed never became final, their files are put back
+	restored, err := util.SweepHoldingAreas(filepath.Join(dataDir, "deleted"))
+	for _, path := range restored {
+		fmt.Println("Restored file deleted in a session that did not end properly:", path)
+	}
+	if err != nil {
+		fmt.Println("Error restoring files deleted in earlier sessions:", err)
+	}
+	holdingArea = util.NewHoldingArea(filepath.Join(dataDir, "deleted"))
+
 	app := app.New()
 	mainWindow = app.NewWindow("Image comparison tool")
 
@@ -154,10 +410,10 @@ func main() {
 		// onImageDeleted
 		func(imageNumber int) {
 			image := image1
-			path := image1Path
+			path, otherPath := image1Path, image2Path
 			if imageNumber == 2 {
 				image = image2
-				path = image2Path
+				path, otherPath = image2Path, image1Path
 			}
 
 			if image == nil {
@@ -165,18 +421,28 @@ func main() {
 			}
 
 			if path != "" {
-				var err error
-				if *useTrashFlag {
-					err = util.MoveFileToTrash(path)
-				} else {
-					err = os.Remove(path)
-				}
-				if err != nil {
+				if err := deleteImages([]string{path}, []string{otherPath}, ""); err != nil {
 					dialog.ShowError(err, mainWindow)
 					return
 				}
-				mainWindow.Close()
+				finishPair()
+			}
+		},
+		// onImageReplaced
+		func(imageNumber int) {
+			path, kept := image1Path, image2Path
+			if imageNumber == 2 {
+				path, kept = image2Path, image1Path
+			}
+			if image1 == nil || image2 == nil || path == "" || kept == "" {
+				return
+			}
+
+			if err := replaceWithLink(path, kept); err != nil {
+				dialog.ShowError(err, mainWindow)
+				return
 			}
+			finishPair()
 		},
 		// onImageIgnored
 		func() {
@@ -184,19 +450,11 @@ func main() {
 				dialog.ShowInformation("No images loaded", "Please load both images before ignoring.", mainWindow)
 				return
 			}
-			// Append a line to a file
-			file, err := os.OpenFile("ignored_images.txt", os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
-			if err != nil {
+			if err := ignorePair(image1Path, image2Path); err != nil {
 				dialog.ShowError(err, mainWindow)
 				return
 			}
-			defer file.Close()
-			_, err = file.WriteString(fmt.Sprintf("%s:%s\n", image1Path, image2Path))
-			if err != nil {
-				dialog.ShowError(err, mainWindow)
-				return
-			}
-			mainWindow.Close()
+			finishPair()
 		},
 		scalingAlgo,
 		*showManagementButtonsFlag,
@@ -237,17 +495,119 @@ func main() {
 		if image1 != nil && image2 != nil {
 			renderComparison()
 		}
+	}, func(ssimMap bool) {
+		if image1 != nil && image2 != nil {
+			renderComparison()
+		}
 	})
+	pixelWiseTab.LinkZoom(comparisonPanel.ZoomGroup())
+
+	loupe = ui.NewLoupe()
+	comparisonPanel.SetOnHover(func(imageNumber int, x, y float64) {
+		pairAlignmentMutex.Lock()
+		alignment := pairAlignment
+		pairAlignmentMutex.Unlock()
+		if imageNumber == 1 {
+			x, y = alignment.FromImage1(x, y)
+		} else {
+			x, y = alignment.FromImage2(x, y)
+		}
+		loupe.ShowAt(x, y)
+	}, loupe.Clear)
+	pixelWiseTab.SetOnHover(loupe.ShowAt, loupe.Clear)
 	layerSliderTab = ui.NewLayerSliderTab(scalingAlgo)
+	blinkTab = ui.NewBlinkTab(scalingAlgo)
+
+	for range 3 {
+		frameScrubbers = append(frameScrubbers, ui.NewFrameScrubber(func(frame int) {
+			if animation1 == nil || animation2 == nil {
+				return
+			}
+			for _, scrubber := range frameScrubbers {
+				scrubber.SetFrame(frame)
+			}
+			selectFrame(frame)
+			renderComparison()
+		}))
+	}
+	pixelWiseTab.AddFrameScrubber(frameScrubbers[0])
+	layerSliderTab.AddFrameScrubber(frameScrubbers[1])
+	blinkTab.AddFrameScrubber(frameScrubbers[2])
 
 	tabs := container.NewAppTabs(
 		container.NewTabItem("Difference", pixelWiseTab.GetContainer()),
 		container.NewTabItem("Layer Slider", layerSliderTab.GetContainer()),
+		container.NewTabItem("Blink", blinkTab.GetContainer()),
 	)
 	tabs.SetTabLocation(container.TabLocationTop)
+	// Only blink while the tab is visible
+	tabs.OnSelected = func(item *container.TabItem) {
+		blinkTab.SetActive(item.Content == blinkTab.GetContainer())
+	}
+	mainWindow.Canvas().SetOnTypedKey(func(event *fyne.KeyEvent) {
+		if event.Name == fyne.KeySpace && tabs.Selected().Content == blinkTab.GetContainer() {
+			blinkTab.Toggle()
+		}
+	})
+
+	queueItemName := "Pair"
+	if *groupModeFlag {
+		queueItemName = "Group"
+	}
+	queueNavigator = ui.NewQueueNavigator(
+		queueItemName,
+		// onPrevious
+		func() {
+			if *groupModeFlag {
+				showQueuedGroup(queueIndex-1, -1)
+			} else {
+				showQueuedPair(queueIndex-1, -1)
+			}
+		},
+		// onNext
+		func() {
+			if *groupModeFlag {
+				showQueuedGroup(queueIndex+1, 1)
+			} else {
+				showQueuedPair(queueIndex+1, 1)
+			}
+		},
+	)
+
+	groupPanel = ui.NewGroupPanel(
+		// onCompare
+		func(i, j int) {
+			loadPair(groupPanel.Member(i).Path, groupPanel.Member(j).Path)
+		},
+		// onOpen
+		func(index int) {
+			if err := exec.Command("xdg-open", groupPanel.Member(index).Path).Start(); err != nil {
+				dialog.ShowError(err, mainWindow)
+			}
+		},
+		deleteGroupMembers,
+		scalingAlgo,
+	)
+
+	undoBar = ui.NewUndoBar(undoDecisions)
+	mainWindow.Canvas().AddShortcut(&desktop.CustomShortcut{KeyName: fyne.KeyZ, Modifier: fyne.KeyModifierShortcutDefault}, func(fyne.Shortcut) {
+		if actionLog.Undoable() > 0 {
+			undoDecisions(1)
+		}
+	})
+	// Deletions can only be undone while the window is open
+	mainWindow.SetOnClosed(func() {
+		if err := holdingArea.Purge(); err != nil {
+			fmt.Println("Error removing deleted files:", err)
+		}
+	})
 
 	mainContent := container.NewVBox(
+		queueNavigator.GetContainer(),
+		undoBar.GetContainer(),
+		groupPanel.GetContainer(),
 		comparisonPanel.GetContainer(),
+		loupe.GetContainer(),
 		tabs,
 	)
 
@@ -268,13 +628,38 @@ func main() {
 			return
 		}
 		fmt.Printf("Loading images from command line arguments: %s, %s\n", img1Path, img2Path)
-		loadingWaitGroup.Add(2)                  // Add two goroutines to the wait group
-		go loadAndRenderImage(img1Path, 0, true) // Load and render the first image
-		go loadAndRenderImage(img2Path, 1, true) // Load and render the second image
-		loadingWaitGroup.Wait()                  // Wait for both goroutines to finish
+		loadPair(img1Path, img2Path)
+		mainWindow.Resize(fyne.NewSize(2560, 1440))
+		mainWindow.CenterOnScreen()
+	} else if *czkawkaFlag != "" {
+		if err := buildQueue(*czkawkaFlag, *sameDimensionsOnlyFlag, *groupModeFlag, *pruneIgnoredFlag); err != nil {
+			fmt.Println("Error reading Czkawka results:", err)
+			return
+		}
+		if *groupModeFlag {
+			if len(groupQueue) == 0 {
+				fmt.Println("No groups left to review in the Czkawka results.")
+				return
+			}
+			fmt.Printf("Loaded %d groups from Czkawka results: %s\n", len(groupQueue), *czkawkaFlag)
+			showQueuedGroup(0, 1)
+		} else {
+			if len(pairQueue) == 0 {
+				fmt.Println("No pairs left to compare in the Czkawka results.")
+				return
+			}
+			fmt.Printf("Loaded %d pairs from Czkawka results: %s\n", len(pairQueue), *czkawkaFlag)
+			var resolutions []util.Resolution
+			if rules != nil {
+				resolutions = resolveQueue(rules)
+			}
+			showQueuedPair(0, 1)
+			if rules != nil {
+				showRulesReport(resolutions)
+			}
+		}
 		mainWindow.Resize(fyne.NewSize(2560, 1440))
 		mainWindow.CenterOnScreen()
-		renderComparison()
 	} else {
 		fmt.Println("No command line arguments provided. Please drag and drop images or use the buttons to load images.")
 	}
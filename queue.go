@@ -0,0 +1,231 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+
+	"imgcomp/ui"
+	"imgcomp/util"
+
+	"fyne.io/fyne/v2/dialog"
+)
+
+// Persistent store of the pairs marked with the Ignore button.
+var ignoreDB *util.IgnoreDB
+
+// Pairs built from a Czkawka results file, and the position of the displayed pair within them.
+var pairQueue []util.ImagePair
+var queueIndex int
+
+// Groups built from a Czkawka results file in group mode, sharing queueIndex with the pair queue.
+var groupQueue [][]util.CzkawkaImage
+
+var queueNavigator *ui.QueueNavigator
+var groupPanel *ui.GroupPanel
+
+// loadPair loads both images in parallel and renders their comparison.
+// If either image fails to load, the pair is cleared so that no action applies to a stale pair,
+// and false is reported.
+func loadPair(path1, path2 string) bool {
+	var loaded [2]bool
+	loadingWaitGroup.Add(2) // Add two goroutines to the wait group
+	go func() { loaded[0] = loadAndRenderImage(path1, 0, true) }()
+	go func() { loaded[1] = loadAndRenderImage(path2, 1, true) }()
+	loadingWaitGroup.Wait() // Wait for both goroutines to finish
+	if !loaded[0] || !loaded[1] {
+		clearPair()
+		return false
+	}
+	preparePair()
+	renderComparison()
+	return true
+}
+
+// clearPair forgets the loaded pair, which disables the actions on it until another pair is loaded.
+func clearPair() {
+	image1Path, image2Path = "", ""
+	image1, image2 = nil, nil
+	original1, original2 = nil, nil
+	animation1, animation2 = nil, nil
+}
+
+// openIgnoreDB opens the ignore database, optionally importing a legacy text ignore list into it.
+func openIgnoreDB(path, importPath string) error {
+	db, err := util.OpenIgnoreDB(path)
+	if err != nil {
+		return err
+	}
+	if importPath != "" {
+		imported, skipped, err := db.ImportTextList(importPath)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Imported %d ignored pairs from %s, skipped %d whose files are missing\n", imported, importPath, skipped)
+	}
+	ignoreDB = db
+	return nil
+}
+
+// buildQueue reads a Czkawka results file and queues every pair that still needs a decision.
+// In group mode, whole groups are queued instead.
+// With pruneIgnored, ignored pairs with a file that is not in the results are forgotten.
+func buildQueue(resultsPath string, sameDimensionsOnly, groupMode, pruneIgnored bool) error {
+	groups, err := util.LoadCzkawkaResults(resultsPath)
+	if err != nil {
+		return err
+	}
+	if groupMode {
+		groupQueue = util.BuildGroupQueue(groups)
+	} else {
+		pairQueue = util.BuildPairQueue(groups, sameDimensionsOnly, ignoreDB)
+	}
+
+	if pruneIgnored {
+		var paths []string
+		for _, group := range groups {
+			for _, img := range group {
+				paths = append(paths, img.Path)
+			}
+		}
+		if removed := ignoreDB.Prune(paths); removed > 0 {
+			fmt.Printf("Pruned %d ignored pairs whose files are not in the Czkawka results\n", removed)
+		}
+	}
+	// Lookups have refreshed the paths of renamed files
+	return ignoreDB.Save()
+}
+
+// showQueuedPair displays the pair at the given queue position.
+// Pairs whose files have been deleted in the meantime or fail to load are skipped in the given direction.
+// It reports false if no pair is left in that direction.
+func showQueuedPair(index, direction int) bool {
+	for ; index >= 0 && index < len(pairQueue); index += direction {
+		pair := pairQueue[index]
+		if !util.FileExists(pair.Image1.Path) || !util.FileExists(pair.Image2.Path) {
+			continue
+		}
+		queueIndex = index
+		queueNavigator.SetProgress(queueIndex, len(pairQueue))
+		if !loadPair(pair.Image1.Path, pair.Image2.Path) {
+			// The error has been shown, move on to a pair that can be reviewed
+			continue
+		}
+		return true
+	}
+	return false
+}
+
+// showQueuedGroup displays the group at the given queue position.
+// Members whose files have been deleted in the meantime are left out,
+// and groups with fewer than two members left are skipped in the given direction.
+// It reports false if no group is left in that direction.
+func showQueuedGroup(index, direction int) bool {
+	for ; index >= 0 && index < len(groupQueue); index += direction {
+		members := util.ExistingMembers(groupQueue[index])
+		if len(members) < 2 {
+			continue
+		}
+		queueIndex = index
+		queueNavigator.SetProgress(queueIndex, len(groupQueue))
+		groupPanel.SetMembers(loadGroupMembers(members))
+		return true
+	}
+	return false
+}
+
+// loadGroupMembers loads the thumbnails and file details of all group members in parallel.
+// Members that fail to load keep the dimensions reported by Czkawka and have no thumbnail.
+func loadGroupMembers(images []util.CzkawkaImage) []ui.GroupMember {
+	members := make([]ui.GroupMember, len(images))
+	var wg sync.WaitGroup
+	for i, img := range images {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			member := ui.GroupMember{
+				Path:   img.Path,
+				Width:  img.Width,
+				Height: img.Height,
+				Size:   img.Size,
+				Format: util.ImageFormat(img.Path),
+			}
+			if fileInfo, err := os.Stat(img.Path); err == nil {
+				member.Size = fileInfo.Size()
+			}
+			if decoded, err := util.LoadImage(img.Path); err != nil {
+				fmt.Println("Error loading image:", err)
+			} else {
+				member.Width = decoded.Bounds().Dx()
+				member.Height = decoded.Bounds().Dy()
+				member.Thumbnail = util.ThumbnailImage(decoded, ui.GroupThumbnailSize, scalingAlgo)
+			}
+			members[i] = member
+		}()
+	}
+	wg.Wait()
+	return members
+}
+
+// deleteGroupMembers asks for confirmation and deletes the marked members of the displayed group,
+// then moves on to the next group.
+func deleteGroupMembers(indices []int) {
+	if len(indices) == 0 {
+		dialog.ShowInformation("Nothing marked", "Mark the images to delete first.", mainWindow)
+		return
+	}
+	var paths, kept []string
+	for _, member := range util.ExistingMembers(groupQueue[queueIndex]) {
+		if slices.ContainsFunc(indices, func(index int) bool { return groupPanel.Member(index).Path == member.Path }) {
+			paths = append(paths, member.Path)
+		} else {
+			kept = append(kept, member.Path)
+		}
+	}
+	if len(kept) == 0 {
+		dialog.ShowInformation("Nothing kept", "Keep at least one image of the group.", mainWindow)
+		return
+	}
+
+	names := make([]string, len(paths))
+	for i, path := range paths {
+		names[i] = filepath.Base(path)
+	}
+	message := fmt.Sprintf("Delete %d images?\n\n%s", len(paths), strings.Join(names, "\n"))
+	dialog.ShowConfirm("Delete marked images", message, func(confirmed bool) {
+		if !confirmed {
+			return
+		}
+		if err := deleteImages(paths, kept, ""); err != nil {
+			dialog.ShowError(err, mainWindow)
+			showQueuedGroup(queueIndex, 1)
+			return
+		}
+		if !showQueuedGroup(queueIndex+1, 1) {
+			showQueueFinished()
+		}
+	}, mainWindow)
+}
+
+// finishPair moves on once the displayed pair has been resolved.
+// In group mode the group is shown again without the deleted members.
+// The window stays open at the end of the queue, or without one, so that decisions can still be undone.
+func finishPair() {
+	if len(groupQueue) > 0 {
+		if !showQueuedGroup(queueIndex, 1) {
+			showQueueFinished()
+		}
+		return
+	}
+	if len(pairQueue) > 0 && !showQueuedPair(queueIndex+1, 1) {
+		showQueueFinished()
+	}
+}
+
+// showQueueFinished tells the user that the whole queue has been reviewed.
+func showQueueFinished() {
+	dialog.ShowInformation("Review finished", "Nothing is left in the queue. Undo the last decisions or close the window.", mainWindow)
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+
+	"imgcomp/util"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+)
+
+// Number of pairs between progress messages while the rules are evaluated.
+const rulesProgressInterval = 100
+
+// resolveQueue runs the rules over the pair queue as a dry run,
+// printing progress and the pairs that could not be evaluated.
+func resolveQueue(rules *util.RuleSet) []util.Resolution {
+	resolutions, errs := rules.ResolveQueue(pairQueue, scalingAlgo, func(done, total int) {
+		if done%rulesProgressInterval == 0 {
+			fmt.Printf("Evaluating rules: %d / %d pairs\n", done, total)
+		}
+	})
+	for _, err := range errs {
+		fmt.Println("Error evaluating rules:", err)
+	}
+	return resolutions
+}
+
+// showRulesReport shows what the rules would delete and applies the resolutions once confirmed.
+func showRulesReport(resolutions []util.Resolution) {
+	if len(resolutions) == 0 {
+		dialog.ShowInformation("Rules dry run", "No pair in the queue is resolved by the rules.", mainWindow)
+		return
+	}
+	report := widget.NewLabel(util.ResolutionReport(resolutions, len(pairQueue)))
+	report.TextStyle = fyne.TextStyle{Monospace: true}
+	scroll := container.NewScroll(report)
+	scroll.SetMinSize(fyne.NewSize(900, 500))
+
+	dialog.ShowCustomConfirm("Rules dry run", "Apply", "Review manually", scroll, func(apply bool) {
+		if apply {
+			applyResolutions(resolutions)
+		}
+	}, mainWindow)
+}
+
+// applyResolutions deletes the files chosen by the rules through the same path as the Delete buttons,
+// so that they can be undone and end up in the audit log, then shows the first pair left to review.
+// Resolutions whose files were removed, replaced or modified since the dry run are skipped.
+func applyResolutions(resolutions []util.Resolution) {
+	var errs []error
+	applied := 0
+	for _, resolution := range resolutions {
+		if !resolution.Unchanged() {
+			fmt.Printf("Skipping %s: it or %s changed since the dry run\n", resolution.Delete.Path, resolution.Keep.Path)
+			continue
+		}
+		if err := deleteImages([]string{resolution.Delete.Path}, []string{resolution.Keep.Path}, resolution.Rule); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		applied++
+	}
+	fmt.Printf("Applied %d of %d rule resolutions\n", applied, len(resolutions))
+
+	if len(errs) > 0 {
+		dialog.ShowError(errors.Join(errs...), mainWindow)
+	}
+	if !showQueuedPair(0, 1) {
+		showQueueFinished()
+	}
+}
@@ -0,0 +1,163 @@
+package ui
+
+import (
+	"image"
+	"imgcomp/util"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/canvas"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/widget"
+)
+
+// Intervals offered between switching the images.
+var blinkIntervals = map[string]time.Duration{
+	"100 ms": 100 * time.Millisecond,
+	"250 ms": 250 * time.Millisecond,
+	"500 ms": 500 * time.Millisecond,
+	"1 s":    time.Second,
+	"2 s":    2 * time.Second,
+}
+
+var blinkIntervalOrder = []string{"100 ms", "250 ms", "500 ms", "1 s", "2 s"}
+
+// BlinkTab alternates between image 1 and image 2 in the same place, so that small localized changes stand out.
+// It only blinks while it is active, i.e. its tab is selected, and not paused.
+type BlinkTab struct {
+	container   *fyne.Container
+	image       *canvas.Image
+	label       *widget.Label
+	pauseButton *widget.Button
+
+	frames   [2]image.Image
+	showing  int
+	interval time.Duration
+	paused   bool
+	active   bool
+	ticker   *time.Ticker
+	done     chan struct{}
+}
+
+func NewBlinkTab(algo util.ScalingAlgorithm) *BlinkTab {
+	b := &BlinkTab{interval: blinkIntervals["500 ms"]}
+
+	b.image = canvas.NewImageFromImage(nil)
+	b.image.FillMode = canvas.ImageFillStretch
+	if algo == util.NearestNeighbor {
+		b.image.ScaleMode = canvas.ImageScalePixels
+	} else {
+		b.image.ScaleMode = canvas.ImageScaleFastest
+	}
+
+	b.label = widget.NewLabel("")
+	b.label.TextStyle = fyne.TextStyle{Bold: true}
+	b.label.Alignment = fyne.TextAlignCenter
+
+	intervalSelect := widget.NewSelect(blinkIntervalOrder, func(selected string) {
+		b.interval = blinkIntervals[selected]
+		b.restart()
+	})
+	intervalSelect.SetSelected("500 ms")
+
+	b.pauseButton = widget.NewButton("Pause", func() {
+		b.SetPaused(!b.paused)
+	})
+
+	b.container = container.NewVBox(
+		b.label,
+		container.NewCenter(b.image),
+		container.NewHBox(
+			widget.NewLabel("Interval"), intervalSelect, b.pauseButton,
+			widget.NewButton("Toggle", b.Toggle),
+			widget.NewLabel("Space switches the images"),
+		),
+	)
+	return b
+}
+
+// Compare prepares both images for blinking. Image 1 is scaled to fit the view and image 2 to the same bounds,
+// so that they line up even if their resolutions differ.
+func (b *BlinkTab) Compare(img1, img2 *image.Image, algo util.ScalingAlgorithm) {
+	sizeX, sizeY := util.GetScaledBounds(img1)
+	b.image.SetMinSize(fyne.NewSize(sizeX, sizeY))
+	b.frames[0] = util.RescaleImageFast(*img1, algo)
+	b.frames[1] = util.MatchBounds(b.frames[0], *img2, algo)
+	b.show(b.showing)
+}
+
+// Toggle switches to the other image and restarts the interval, so that it stays visible for a full interval.
+func (b *BlinkTab) Toggle() {
+	b.show(1 - b.showing)
+	if b.ticker != nil {
+		b.ticker.Reset(b.interval)
+	}
+}
+
+// SetPaused stops or resumes blinking, Toggle still switches the images while paused.
+func (b *BlinkTab) SetPaused(paused bool) {
+	b.paused = paused
+	if paused {
+		b.pauseButton.SetText("Resume")
+	} else {
+		b.pauseButton.SetText("Pause")
+	}
+	b.restart()
+}
+
+// SetActive starts blinking when the tab is shown and stops it when it is hidden.
+func (b *BlinkTab) SetActive(active bool) {
+	b.active = active
+	b.restart()
+}
+
+func (b *BlinkTab) show(index int) {
+	b.showing = index
+	b.image.Image = b.frames[index]
+	b.image.Refresh()
+	if index == 0 {
+		b.label.SetText("Image 1")
+	} else {
+		b.label.SetText("Image 2")
+	}
+}
+
+// restart stops the running ticker and starts a new one with the current interval if the tab should blink.
+func (b *BlinkTab) restart() {
+	if b.ticker != nil {
+		b.ticker.Stop()
+		close(b.done)
+		b.ticker = nil
+	}
+	if b.paused || !b.active {
+		return
+	}
+
+	ticker := time.NewTicker(b.interval)
+	done := make(chan struct{})
+	b.ticker, b.done = ticker, done
+	go func() {
+		for {
+			select {
+			case <-ticker.C:
+				fyne.Do(func() {
+					// Ticks already queued when the ticker was replaced are dropped
+					if b.ticker == ticker {
+						b.show(1 - b.showing)
+					}
+				})
+			case <-done:
+				return
+			}
+		}
+	}()
+}
+
+// AddFrameScrubber places a frame scrubber below the blinking image.
+func (b *BlinkTab) AddFrameScrubber(scrubber *FrameScrubber) {
+	b.container.Add(scrubber.GetContainer())
+}
+
+func (b *BlinkTab) GetContainer() *fyne.Container {
+	return b.container
+}
@@ -0,0 +1,282 @@
+package custom
+
+import (
+	"image"
+	"image/color"
+	"imgcomp/util"
+	"math"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/canvas"
+	"fyne.io/fyne/v2/driver/desktop"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+)
+
+// WipeMode is how a WipeView reveals the layer over the base image.
+type WipeMode int
+
+const (
+	// WipeHorizontal shows the layer left of a vertical divider.
+	WipeHorizontal WipeMode = iota
+	// WipeVertical shows the layer above a horizontal divider.
+	WipeVertical
+	// WipeSpotlight shows the layer inside a circle following the mouse.
+	WipeSpotlight
+)
+
+const wipeTagPadding = 4
+
+// WipeView shows a layer image over a base image, revealed up to a divider that can be dragged on the image,
+// or inside a spotlight around the mouse. Both images are stretched to the size of the widget.
+type WipeView struct {
+	widget.BaseWidget
+	base     image.Image
+	layer    image.Image
+	mode     WipeMode
+	position float64 // Divider position as a fraction of the width or height
+	centerX  float64 // Spotlight center as a fraction of the width
+	centerY  float64 // Spotlight center as a fraction of the height
+	radius   float64 // Spotlight radius as a fraction of the smaller side
+	minSize  fyne.Size
+	onMoved  func(position float64)
+
+	baseCanvas  *canvas.Image
+	layerCanvas *canvas.Image
+	divider     *canvas.Line
+	spotlight   *canvas.Circle
+	tags        [2]*wipeTag // Names of the base and the layer
+}
+
+// wipeTag is a name drawn on a translucent background over the images.
+type wipeTag struct {
+	background *canvas.Rectangle
+	text       *canvas.Text
+}
+
+func newWipeTag(name string) *wipeTag {
+	text := canvas.NewText(name, color.White)
+	text.TextStyle = fyne.TextStyle{Bold: true}
+	return &wipeTag{background: canvas.NewRectangle(color.NRGBA{A: 160}), text: text}
+}
+
+func (t *wipeTag) size() fyne.Size {
+	return t.text.MinSize().AddWidthHeight(2*wipeTagPadding, 2*wipeTagPadding)
+}
+
+func (t *wipeTag) move(pos fyne.Position) {
+	t.background.Move(pos)
+	t.background.Resize(t.size())
+	t.text.Move(pos.AddXY(wipeTagPadding, wipeTagPadding))
+	t.text.Resize(t.text.MinSize())
+}
+
+// NewWipeView creates a WipeView naming the base and the layer image.
+// onMoved is called with the new divider position when the divider is dragged.
+func NewWipeView(baseName, layerName string, onMoved func(position float64), algo util.ScalingAlgorithm) *WipeView {
+	w := &WipeView{
+		position: 0.5,
+		centerX:  0.5,
+		centerY:  0.5,
+		radius:   0.25,
+		onMoved:  onMoved,
+	}
+	w.baseCanvas = canvas.NewImageFromImage(nil)
+	w.layerCanvas = canvas.NewImageFromImage(nil)
+	for _, img := range []*canvas.Image{w.baseCanvas, w.layerCanvas} {
+		img.FillMode = canvas.ImageFillStretch
+		if algo == util.NearestNeighbor {
+			img.ScaleMode = canvas.ImageScalePixels
+		} else {
+			img.ScaleMode = canvas.ImageScaleFastest
+		}
+	}
+	w.divider = canvas.NewLine(theme.Color(theme.ColorNamePrimary))
+	w.divider.StrokeWidth = 2
+	w.spotlight = canvas.NewCircle(color.Transparent)
+	w.spotlight.StrokeColor = theme.Color(theme.ColorNamePrimary)
+	w.spotlight.StrokeWidth = 2
+	w.tags = [2]*wipeTag{newWipeTag(baseName), newWipeTag(layerName)}
+	w.ExtendBaseWidget(w)
+	return w
+}
+
+// SetImages sets the base image and the layer revealed over it. Without a layer only the base is shown.
+func (w *WipeView) SetImages(base, layer image.Image) {
+	w.base = base
+	w.layer = layer
+	w.baseCanvas.Image = base
+	w.baseCanvas.Refresh()
+	w.updateLayer()
+}
+
+// SetMode switches between the horizontal and vertical divider and the spotlight.
+func (w *WipeView) SetMode(mode WipeMode) {
+	w.mode = mode
+	w.updateLayer()
+}
+
+// SetPosition moves the divider, as a fraction of the width or height.
+func (w *WipeView) SetPosition(position float64) {
+	position = math.Max(0, math.Min(1, position))
+	if position == w.position {
+		return
+	}
+	w.position = position
+	w.updateLayer()
+}
+
+// SetSpotlightRadius sets the radius of the spotlight as a fraction of the smaller side of the image.
+func (w *WipeView) SetSpotlightRadius(radius float64) {
+	w.radius = math.Max(0, math.Min(1, radius))
+	w.updateLayer()
+}
+
+// SetMinSize sets the minimum size of the widget.
+func (w *WipeView) SetMinSize(size fyne.Size) {
+	w.minSize = size
+	w.Refresh()
+}
+
+// updateLayer masks the layer for the current mode and moves the divider or spotlight outline.
+func (w *WipeView) updateLayer() {
+	if w.layer == nil {
+		w.layerCanvas.Image = nil
+	} else {
+		bounds := w.layer.Bounds()
+		switch w.mode {
+		case WipeHorizontal, WipeVertical:
+			w.layerCanvas.Image = util.WipeImage(w.layer, w.position, w.mode == WipeVertical)
+		case WipeSpotlight:
+			center := image.Pt(int(w.centerX*float64(bounds.Dx())), int(w.centerY*float64(bounds.Dy())))
+			radius := int(w.radius * float64(min(bounds.Dx(), bounds.Dy())))
+			w.layerCanvas.Image = util.SpotlightImage(w.layer, center, radius)
+		}
+	}
+	w.layerCanvas.Refresh()
+	w.Refresh()
+}
+
+// moveTo moves the divider or the spotlight to a position on the widget.
+func (w *WipeView) moveTo(pos fyne.Position) {
+	size := w.Size()
+	if w.layer == nil || size.Width == 0 || size.Height == 0 {
+		return
+	}
+	switch w.mode {
+	case WipeHorizontal:
+		w.SetPosition(float64(pos.X / size.Width))
+	case WipeVertical:
+		w.SetPosition(float64(pos.Y / size.Height))
+	case WipeSpotlight:
+		w.centerX = math.Max(0, math.Min(1, float64(pos.X/size.Width)))
+		w.centerY = math.Max(0, math.Min(1, float64(pos.Y/size.Height)))
+		w.updateLayer()
+		return
+	}
+	if w.onMoved != nil {
+		w.onMoved(w.position)
+	}
+}
+
+// Dragged moves the divider, or the spotlight, along with the mouse.
+func (w *WipeView) Dragged(e *fyne.DragEvent) {
+	w.moveTo(e.Position)
+}
+
+// DragEnd is part of the fyne.Draggable interface.
+func (w *WipeView) DragEnd() {}
+
+// Tapped moves the divider, or the spotlight, to the tapped position.
+func (w *WipeView) Tapped(e *fyne.PointEvent) {
+	w.moveTo(e.Position)
+}
+
+// MouseIn is part of the desktop.Hoverable interface.
+func (w *WipeView) MouseIn(e *desktop.MouseEvent) {
+	w.MouseMoved(e)
+}
+
+// MouseMoved makes the spotlight follow the mouse.
+func (w *WipeView) MouseMoved(e *desktop.MouseEvent) {
+	if w.mode == WipeSpotlight {
+		w.moveTo(e.Position)
+	}
+}
+
+// MouseOut is part of the desktop.Hoverable interface.
+func (w *WipeView) MouseOut() {}
+
+// MinSize returns the size set with SetMinSize.
+func (w *WipeView) MinSize() fyne.Size {
+	return w.minSize
+}
+
+// CreateRenderer is a Fyne internal method to create a renderer for the widget.
+func (w *WipeView) CreateRenderer() fyne.WidgetRenderer {
+	return &wipeViewRenderer{view: w}
+}
+
+// wipeViewRenderer lays out the images, the divider or spotlight outline and the tags.
+type wipeViewRenderer struct {
+	view *WipeView
+}
+
+func (r *wipeViewRenderer) Layout(size fyne.Size) {
+	w := r.view
+	w.baseCanvas.Resize(size)
+	w.layerCanvas.Resize(size)
+
+	w.divider.Hidden = w.layer == nil || w.mode == WipeSpotlight
+	w.spotlight.Hidden = w.layer == nil || w.mode != WipeSpotlight
+	// Without a layer there are no sides to name
+	for _, tag := range w.tags {
+		tag.background.Hidden = w.base == nil || w.layer == nil
+		tag.text.Hidden = tag.background.Hidden
+	}
+
+	// The layer is shown before the divider and inside the spotlight, the base everywhere else
+	baseTag, layerTag := w.tags[0], w.tags[1]
+	switch w.mode {
+	case WipeHorizontal:
+		x := size.Width * float32(w.position)
+		w.divider.Position1 = fyne.NewPos(x, 0)
+		w.divider.Position2 = fyne.NewPos(x, size.Height)
+		layerTag.move(fyne.NewPos(0, 0))
+		baseTag.move(fyne.NewPos(size.Width-baseTag.size().Width, 0))
+	case WipeVertical:
+		y := size.Height * float32(w.position)
+		w.divider.Position1 = fyne.NewPos(0, y)
+		w.divider.Position2 = fyne.NewPos(size.Width, y)
+		layerTag.move(fyne.NewPos(0, 0))
+		baseTag.move(fyne.NewPos(0, size.Height-baseTag.size().Height))
+	case WipeSpotlight:
+		radius := float32(w.radius) * fyne.Min(size.Width, size.Height)
+		center := fyne.NewPos(size.Width*float32(w.centerX), size.Height*float32(w.centerY))
+		w.spotlight.Move(center.SubtractXY(radius, radius))
+		w.spotlight.Resize(fyne.NewSquareSize(2 * radius))
+		baseTag.move(fyne.NewPos(0, 0))
+		layerTag.move(center.SubtractXY(layerTag.size().Width/2, radius+layerTag.size().Height))
+	}
+}
+
+func (r *wipeViewRenderer) MinSize() fyne.Size {
+	return r.view.minSize
+}
+
+func (r *wipeViewRenderer) Refresh() {
+	r.Layout(r.view.Size())
+	for _, obj := range r.Objects() {
+		canvas.Refresh(obj)
+	}
+}
+
+func (r *wipeViewRenderer) Objects() []fyne.CanvasObject {
+	w := r.view
+	return []fyne.CanvasObject{
+		w.baseCanvas, w.layerCanvas, w.divider, w.spotlight,
+		w.tags[0].background, w.tags[0].text, w.tags[1].background, w.tags[1].text,
+	}
+}
+
+func (r *wipeViewRenderer) Destroy() {}
@@ -0,0 +1,410 @@
+package custom
+
+import (
+	"image"
+	"imgcomp/util"
+	"math"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/canvas"
+	"fyne.io/fyne/v2/driver/desktop"
+	"fyne.io/fyne/v2/widget"
+	"github.com/disintegration/imaging"
+)
+
+const (
+	maxZoom        = 256  // Largest zoom relative to fitting the whole image into the widget
+	zoomStep       = 1.25 // Zoom factor applied per mouse wheel notch
+	minPyramidSize = 64   // Downscaled copies stop once the image is smaller than this
+)
+
+// ZoomGroup keeps the viewports of several ZoomableImages locked together, so they always show the same region.
+// The viewport is stored relative to a region all images share, by default the whole of each image,
+// so images of different resolutions stay aligned.
+type ZoomGroup struct {
+	images  []*ZoomableImage
+	zoom    float64 // 1 fits the whole region into the widget
+	centerX float64 // Center of the viewport as a fraction of the region width
+	centerY float64 // Center of the viewport as a fraction of the region height
+
+	// OnChanged is called after the zoom or the position of the viewport changed.
+	OnChanged func()
+}
+
+// NewZoomGroup creates a group showing the whole image.
+func NewZoomGroup() *ZoomGroup {
+	return &ZoomGroup{zoom: 1, centerX: 0.5, centerY: 0.5}
+}
+
+// Add makes the images follow the viewport of the group.
+func (g *ZoomGroup) Add(images ...*ZoomableImage) {
+	for _, img := range images {
+		img.group = g
+		g.images = append(g.images, img)
+		img.raster.Refresh()
+	}
+}
+
+// Zoom returns the zoom relative to fitting the whole image into the widget.
+func (g *ZoomGroup) Zoom() float64 {
+	return g.zoom
+}
+
+// Reset shows the whole region again.
+func (g *ZoomGroup) Reset() {
+	g.set(1, 0.5, 0.5)
+}
+
+// set moves the viewport, keeping its center on the region or on any part of an image outside it.
+func (g *ZoomGroup) set(zoom, centerX, centerY float64) {
+	minX, minY, maxX, maxY := 0.0, 0.0, 1.0, 1.0
+	for _, img := range g.images {
+		x0, y0 := img.mapping.toRegion(0, 0)
+		x1, y1 := img.mapping.toRegion(1, 1)
+		minX, maxX = min(minX, x0, x1), max(maxX, x0, x1)
+		minY, maxY = min(minY, y0, y1), max(maxY, y0, y1)
+	}
+	g.zoom = math.Max(1, math.Min(maxZoom, zoom))
+	g.centerX = math.Max(minX, math.Min(maxX, centerX))
+	g.centerY = math.Max(minY, math.Min(maxY, centerY))
+	for _, img := range g.images {
+		img.raster.Refresh()
+	}
+	if g.OnChanged != nil {
+		g.OnChanged()
+	}
+}
+
+// viewMapping is the affine map from positions on an image, as fractions of its width and height,
+// to fractions of the region shown by its group. It covers crops, mirroring and rotations by multiples of 90°.
+type viewMapping struct {
+	originX, originY float64 // Region position of the top left corner of the image
+	xX, xY           float64 // Change of the region position across the image width
+	yX, yY           float64 // Change of the region position across the image height
+}
+
+// wholeImage maps the image onto the region one to one.
+var wholeImage = viewMapping{xX: 1, yY: 1}
+
+// newViewMapping samples an affine map at the corners of the image.
+func newViewMapping(toRegion func(x, y float64) (float64, float64)) viewMapping {
+	originX, originY := toRegion(0, 0)
+	rightX, rightY := toRegion(1, 0)
+	bottomX, bottomY := toRegion(0, 1)
+	return viewMapping{
+		originX: originX, originY: originY,
+		xX: rightX - originX, xY: rightY - originY,
+		yX: bottomX - originX, yY: bottomY - originY,
+	}
+}
+
+func (m viewMapping) toRegion(x, y float64) (float64, float64) {
+	return m.originX + x*m.xX + y*m.yX, m.originY + x*m.xY + y*m.yY
+}
+
+func (m viewMapping) fromRegion(x, y float64) (float64, float64) {
+	det := m.xX*m.yY - m.yX*m.xY
+	x, y = x-m.originX, y-m.originY
+	return (m.yY*x - m.yX*y) / det, (m.xX*y - m.xY*x) / det
+}
+
+// regionSize returns the width and height of the region as fractions of the image.
+func (m viewMapping) regionSize() (float64, float64) {
+	x0, y0 := m.fromRegion(0, 0)
+	x1, y1 := m.fromRegion(1, 1)
+	return math.Abs(x1 - x0), math.Abs(y1 - y0)
+}
+
+// ZoomableImage displays an image that can be zoomed with the mouse wheel and panned by dragging.
+// Double tapping switches between fitting the whole image and showing it at its actual pixels.
+type ZoomableImage struct {
+	widget.BaseWidget
+	raster   *canvas.Raster
+	levels   []*image.NRGBA // The image and copies halving its size, used when zoomed out
+	algo     util.ScalingAlgorithm
+	maxPool  bool
+	group    *ZoomGroup
+	mapping  viewMapping
+	onTapped func()
+	minSize  fyne.Size
+
+	// OnHover is called with the position under the mouse as a fraction of the image width and height.
+	OnHover func(x, y float64)
+	// OnHoverEnd is called when the mouse leaves the image.
+	OnHoverEnd func()
+}
+
+// NewZoomableImage creates a new ZoomableImage widget with a viewport of its own.
+func NewZoomableImage(tapped func(), algo util.ScalingAlgorithm) *ZoomableImage {
+	z := &ZoomableImage{
+		onTapped: tapped,
+		algo:     algo,
+		mapping:  wholeImage,
+	}
+	z.raster = canvas.NewRaster(z.render)
+	z.raster.ScaleMode = canvas.ImageScalePixels
+	NewZoomGroup().Add(z)
+	z.ExtendBaseWidget(z)
+	return z
+}
+
+// SetMinSize sets the minimum size of the widget.
+func (z *ZoomableImage) SetMinSize(size fyne.Size) {
+	z.minSize = size
+	z.Refresh()
+}
+
+// SetMaxPooling makes zoomed out views keep the brightest pixel of each area instead of averaging them,
+// so that small differences in a difference image do not disappear.
+func (z *ZoomableImage) SetMaxPooling(maxPool bool) {
+	z.maxPool = maxPool
+}
+
+// SetImage replaces the displayed image, keeping the viewport of the group.
+func (z *ZoomableImage) SetImage(img image.Image) {
+	z.levels = nil
+	if img != nil {
+		filter := imaging.Box
+		if z.algo == util.NearestNeighbor {
+			filter = imaging.NearestNeighbor
+		}
+		level := imaging.Clone(img)
+		z.levels = append(z.levels, level)
+		for level.Bounds().Dx() >= 2*minPyramidSize && level.Bounds().Dy() >= 2*minPyramidSize {
+			if z.maxPool {
+				level = util.MaxPoolDownscale(level, 2)
+			} else {
+				level = imaging.Resize(level, level.Bounds().Dx()/2, level.Bounds().Dy()/2, filter)
+			}
+			z.levels = append(z.levels, level)
+		}
+	}
+	z.raster.Refresh()
+}
+
+// SetRegion makes the image follow the viewport of its group through toRegion, which maps positions on the image,
+// as fractions of its width and height, to fractions of the region the group shows.
+// Images that are crops or rotated copies of each other then show the same spot. Nil maps the whole image onto the region.
+func (z *ZoomableImage) SetRegion(toRegion func(x, y float64) (float64, float64)) {
+	z.mapping = wholeImage
+	if toRegion != nil {
+		z.mapping = newViewMapping(toRegion)
+	}
+	z.raster.Refresh()
+}
+
+// ActualSize zooms the group so that one pixel of this image covers one pixel of the screen.
+func (z *ZoomableImage) ActualSize() {
+	if len(z.levels) == 0 {
+		return
+	}
+	size := z.Size()
+	z.group.set(1/(z.pixelScale()*z.regionZoom(float64(size.Width), float64(size.Height))), z.group.centerX, z.group.centerY)
+}
+
+// Scale returns how many screen pixels one pixel of the image covers at the current zoom.
+func (z *ZoomableImage) Scale() float64 {
+	if len(z.levels) == 0 {
+		return 0
+	}
+	size := z.Size()
+	return z.pixelScale() * z.group.zoom * z.regionZoom(float64(size.Width), float64(size.Height))
+}
+
+// regionZoom returns the zoom of the image, relative to fitting all of it into an area of the given size,
+// at which the region of the group fits instead.
+func (z *ZoomableImage) regionZoom(width, height float64) float64 {
+	bounds := z.levels[0].Bounds()
+	regionWidth, regionHeight := z.mapping.regionSize()
+	regionFit := math.Min(width/(regionWidth*float64(bounds.Dx())), height/(regionHeight*float64(bounds.Dy())))
+	if math.IsNaN(regionFit) || math.IsInf(regionFit, 0) {
+		return 1
+	}
+	return regionFit / z.fitScale(width, height)
+}
+
+// center returns the center of the viewport as fractions of the image width and height.
+func (z *ZoomableImage) center() (float64, float64) {
+	return z.mapping.fromRegion(z.group.centerX, z.group.centerY)
+}
+
+// pixelScale returns the screen pixels per image pixel when the whole image fits into the widget.
+func (z *ZoomableImage) pixelScale() float64 {
+	canvasScale := float32(1)
+	if c := fyne.CurrentApp().Driver().CanvasForObject(z); c != nil {
+		canvasScale = c.Scale()
+	}
+	size := z.Size()
+	return z.fitScale(float64(size.Width*canvasScale), float64(size.Height*canvasScale))
+}
+
+// fitScale returns the scale that fits the whole image into an area of the given size.
+func (z *ZoomableImage) fitScale(width, height float64) float64 {
+	bounds := z.levels[0].Bounds()
+	return math.Min(width/float64(bounds.Dx()), height/float64(bounds.Dy()))
+}
+
+// Scrolled zooms in or out around the mouse cursor, keeping the point under it in place.
+func (z *ZoomableImage) Scrolled(e *fyne.ScrollEvent) {
+	if len(z.levels) == 0 || e.Scrolled.DY == 0 {
+		return
+	}
+	zoom := z.group.zoom * zoomStep
+	if e.Scrolled.DY < 0 {
+		zoom = z.group.zoom / zoomStep
+	}
+	zoom = math.Max(1, math.Min(maxZoom, zoom))
+
+	bounds := z.levels[0].Bounds()
+	size := z.Size()
+	fit := z.fitScale(float64(size.Width), float64(size.Height)) * z.regionZoom(float64(size.Width), float64(size.Height))
+	offsetX := float64(e.Position.X - size.Width/2)
+	offsetY := float64(e.Position.Y - size.Height/2)
+	// Image coordinates under the cursor stay the same before and after zooming
+	centerX, centerY := z.center()
+	pointX := centerX*float64(bounds.Dx()) + offsetX/(fit*z.group.zoom)
+	pointY := centerY*float64(bounds.Dy()) + offsetY/(fit*z.group.zoom)
+	regionX, regionY := z.mapping.toRegion(
+		(pointX-offsetX/(fit*zoom))/float64(bounds.Dx()),
+		(pointY-offsetY/(fit*zoom))/float64(bounds.Dy()),
+	)
+	z.group.set(zoom, regionX, regionY)
+}
+
+// Dragged pans the image along with the mouse.
+func (z *ZoomableImage) Dragged(e *fyne.DragEvent) {
+	if len(z.levels) == 0 {
+		return
+	}
+	bounds := z.levels[0].Bounds()
+	size := z.Size()
+	scale := z.fitScale(float64(size.Width), float64(size.Height)) * z.group.zoom * z.regionZoom(float64(size.Width), float64(size.Height))
+	centerX, centerY := z.center()
+	regionX, regionY := z.mapping.toRegion(
+		centerX-float64(e.Dragged.DX)/scale/float64(bounds.Dx()),
+		centerY-float64(e.Dragged.DY)/scale/float64(bounds.Dy()),
+	)
+	z.group.set(z.group.zoom, regionX, regionY)
+}
+
+// DragEnd is part of the fyne.Draggable interface.
+func (z *ZoomableImage) DragEnd() {}
+
+// MouseIn is part of the desktop.Hoverable interface.
+func (z *ZoomableImage) MouseIn(e *desktop.MouseEvent) {
+	z.MouseMoved(e)
+}
+
+// MouseMoved reports the image position under the mouse to OnHover, or calls OnHoverEnd outside the image.
+func (z *ZoomableImage) MouseMoved(e *desktop.MouseEvent) {
+	if len(z.levels) == 0 {
+		return
+	}
+	size := z.Size()
+	scale := z.fitScale(float64(size.Width), float64(size.Height)) * z.group.zoom * z.regionZoom(float64(size.Width), float64(size.Height))
+	bounds := z.levels[0].Bounds()
+	centerX, centerY := z.center()
+	x := centerX + float64(e.Position.X-size.Width/2)/scale/float64(bounds.Dx())
+	y := centerY + float64(e.Position.Y-size.Height/2)/scale/float64(bounds.Dy())
+	if x < 0 || x >= 1 || y < 0 || y >= 1 {
+		z.MouseOut()
+		return
+	}
+	if z.OnHover != nil {
+		z.OnHover(x, y)
+	}
+}
+
+// MouseOut is part of the desktop.Hoverable interface.
+func (z *ZoomableImage) MouseOut() {
+	if z.OnHoverEnd != nil {
+		z.OnHoverEnd()
+	}
+}
+
+// Tapped is part of the fyne.Tappable interface.
+func (z *ZoomableImage) Tapped(*fyne.PointEvent) {
+	if z.onTapped != nil {
+		z.onTapped()
+	}
+}
+
+// DoubleTapped switches between fitting the whole image and showing its actual pixels.
+func (z *ZoomableImage) DoubleTapped(*fyne.PointEvent) {
+	if z.group.zoom > 1 {
+		z.group.Reset()
+	} else {
+		z.ActualSize()
+	}
+}
+
+// MinSize returns the size set with SetMinSize.
+func (z *ZoomableImage) MinSize() fyne.Size {
+	return z.minSize
+}
+
+// CreateRenderer is a Fyne internal method to create a renderer for the widget.
+func (z *ZoomableImage) CreateRenderer() fyne.WidgetRenderer {
+	return widget.NewSimpleRenderer(z.raster)
+}
+
+// render draws the region of the image inside the viewport, w and h are in screen pixels.
+// Zoomed in, every image pixel becomes a sharp block so that compression artefacts stay visible.
+// Zoomed out, pixels come from the smallest downscaled copy that still has at least one pixel per screen pixel.
+// Each screen pixel shows the copy's pixel under its center, or with max pooling the brightest of all it covers.
+func (z *ZoomableImage) render(w, h int) image.Image {
+	out := image.NewNRGBA(image.Rect(0, 0, w, h))
+	if len(z.levels) == 0 || w == 0 || h == 0 {
+		return out
+	}
+
+	fullWidth := float64(z.levels[0].Bounds().Dx())
+	scale := z.fitScale(float64(w), float64(h)) * z.group.zoom * z.regionZoom(float64(w), float64(h))
+	level := z.levels[0]
+	for _, smaller := range z.levels[1:] {
+		if scale*fullWidth/float64(smaller.Bounds().Dx()) > 1 {
+			break
+		}
+		level = smaller
+	}
+	levelWidth, levelHeight := level.Bounds().Dx(), level.Bounds().Dy()
+	levelScale := scale * fullWidth / float64(levelWidth)
+	centerX, centerY := z.center()
+	originX := centerX*float64(levelWidth) - float64(w)/2/levelScale
+	originY := centerY*float64(levelHeight) - float64(h)/2/levelScale
+
+	columns := z.footprints(originX, levelScale, w, levelWidth)
+	rows := z.footprints(originY, levelScale, h, levelHeight)
+	for y, row := range rows {
+		dst := out.Pix[y*out.Stride:]
+		for sy := row[0]; sy < row[1]; sy++ {
+			src := level.Pix[sy*level.Stride:]
+			for x, column := range columns {
+				pixel := dst[x*4 : x*4+4]
+				for sx := column[0]; sx < column[1]; sx++ {
+					for c := range pixel {
+						pixel[c] = max(pixel[c], src[sx*4+c])
+					}
+				}
+			}
+		}
+	}
+	return out
+}
+
+// footprints returns for each of n screen pixels along one axis the range [first, last) of level pixels it shows.
+// Ranges outside the level are empty.
+func (z *ZoomableImage) footprints(origin, levelScale float64, n, size int) [][2]int {
+	spans := make([][2]int, n)
+	for i := range spans {
+		if z.maxPool && levelScale < 1 {
+			first := int(math.Floor(origin + float64(i)/levelScale))
+			last := max(first+1, int(math.Floor(origin+float64(i+1)/levelScale)))
+			spans[i] = [2]int{max(0, first), min(size, last)}
+		} else {
+			center := int(math.Floor(origin + (float64(i)+0.5)/levelScale))
+			spans[i] = [2]int{max(0, center), min(size, center+1)}
+		}
+	}
+	return spans
+}
@@ -0,0 +1,86 @@
+package ui
+
+import (
+	"fmt"
+	"imgcomp/util"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/widget"
+)
+
+// FrameScrubber lets the user pick which frame pair of two animations is compared.
+// It stays hidden while neither image is animated.
+type FrameScrubber struct {
+	container    *fyne.Container
+	slider       *widget.Slider
+	frameLabel   *widget.Label
+	summaryLabel *widget.Label
+
+	frame      int
+	comparison util.AnimationComparison
+	onChanged  func(frame int)
+}
+
+func NewFrameScrubber(onChanged func(frame int)) *FrameScrubber {
+	f := &FrameScrubber{onChanged: onChanged}
+
+	f.frameLabel = widget.NewLabel("")
+	f.summaryLabel = widget.NewLabel("")
+	f.slider = widget.NewSlider(0, 1)
+	f.slider.Step = 1
+	f.slider.OnChanged = func(val float64) {
+		frame := int(val)
+		if frame == f.frame {
+			return
+		}
+		f.frame = frame
+		f.updateLabel()
+		if f.onChanged != nil {
+			f.onChanged(frame)
+		}
+	}
+
+	f.container = container.NewVBox(
+		f.summaryLabel,
+		container.NewBorder(nil, nil, nil, f.frameLabel, f.slider),
+	)
+	f.container.Hide()
+	return f
+}
+
+// SetComparison configures the scrubber for a new pair of animations and resets it to the first frame.
+func (f *FrameScrubber) SetComparison(comparison util.AnimationComparison) {
+	f.comparison = comparison
+	if !comparison.Animated() {
+		f.container.Hide()
+		return
+	}
+
+	f.frame = 0
+	f.slider.Max = float64(max(len(comparison.FrameMAE)-1, 1))
+	f.slider.SetValue(0)
+	f.summaryLabel.SetText(comparison.Summary())
+	f.updateLabel()
+	f.container.Show()
+}
+
+// SetFrame moves the scrubber to the given frame without notifying the change callback.
+func (f *FrameScrubber) SetFrame(frame int) {
+	f.frame = frame
+	f.slider.SetValue(float64(frame))
+	f.updateLabel()
+}
+
+// updateLabel shows the current frame position and its MAE.
+func (f *FrameScrubber) updateLabel() {
+	total := len(f.comparison.FrameMAE)
+	if f.frame >= total {
+		return
+	}
+	f.frameLabel.SetText(fmt.Sprintf("Frame %d/%d | MAE: %.2f", f.frame+1, total, f.comparison.FrameMAE[f.frame]))
+}
+
+func (f *FrameScrubber) GetContainer() *fyne.Container {
+	return f.container
+}
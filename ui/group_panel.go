@@ -0,0 +1,161 @@
+package ui
+
+import (
+	"fmt"
+	"image"
+	"imgcomp/ui/custom"
+	"imgcomp/util"
+	"path/filepath"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/widget"
+)
+
+// GroupThumbnailSize is the side length of the square the member thumbnails are fitted into.
+const GroupThumbnailSize = 200
+
+// Number of members shown per row of the grid.
+const groupColumns = 5
+
+// GroupMember is one image of a duplicate group.
+type GroupMember struct {
+	Path      string
+	Width     int
+	Height    int
+	Size      int64
+	Format    string
+	Thumbnail image.Image // Fitted into GroupThumbnailSize, nil if the image could not be loaded
+}
+
+// GroupPanel shows all members of a duplicate group as a grid.
+// Any two members can be picked for comparison, and members can be marked for deletion in one pass.
+// It stays hidden until a group is set.
+type GroupPanel struct {
+	container *fyne.Container
+	grid      *fyne.Container
+	title     *widget.Label
+
+	members       []GroupMember
+	compareChecks []*widget.Check
+	deleteChecks  []*widget.Check
+	compared      []int // Members picked for comparison, oldest first
+
+	onCompare func(i, j int)
+	onOpen    func(index int)
+	algo      util.ScalingAlgorithm
+}
+
+func NewGroupPanel(onCompare func(i, j int), onOpen func(index int), onDeleteMarked func(indices []int), algo util.ScalingAlgorithm) *GroupPanel {
+	p := &GroupPanel{onCompare: onCompare, onOpen: onOpen, algo: algo}
+	p.title = widget.NewLabel("")
+	p.title.TextStyle = fyne.TextStyle{Bold: true}
+	p.grid = container.NewGridWithColumns(groupColumns)
+
+	deleteButton := widget.NewButton("Delete marked", func() {
+		onDeleteMarked(p.Marked())
+	})
+	deleteButton.Importance = widget.DangerImportance
+
+	p.container = container.NewVBox(
+		container.NewBorder(nil, nil, p.title, deleteButton),
+		p.grid,
+	)
+	p.container.Hide()
+	return p
+}
+
+// SetMembers replaces the displayed group and picks the first two members for comparison.
+func (p *GroupPanel) SetMembers(members []GroupMember) {
+	p.members = members
+	p.compareChecks = make([]*widget.Check, len(members))
+	p.deleteChecks = make([]*widget.Check, len(members))
+	p.compared = nil
+	p.title.SetText(fmt.Sprintf("Group of %d images, pick two to compare and mark the ones to delete", len(members)))
+
+	p.grid.RemoveAll()
+	for i, member := range members {
+		p.grid.Add(p.newMemberCard(i, member))
+	}
+	for i := range min(2, len(members)) {
+		p.compareChecks[i].SetChecked(true)
+	}
+	p.container.Show()
+	p.container.Refresh()
+}
+
+// newMemberCard builds the thumbnail, description and checkboxes of one member.
+func (p *GroupPanel) newMemberCard(index int, member GroupMember) fyne.CanvasObject {
+	thumbnail := custom.NewClickableImage(nil, func() {
+		p.onOpen(index)
+	}, p.algo)
+	thumbnail.SetImageMinSize(fyne.NewSize(GroupThumbnailSize, GroupThumbnailSize))
+	if member.Thumbnail != nil {
+		thumbnail.SetImage(member.Thumbnail)
+	}
+
+	description := widget.NewLabel(fmt.Sprintf(
+		"%s\n%dx%d | %s bytes | %s",
+		filepath.Base(member.Path),
+		member.Width,
+		member.Height,
+		util.FormatIntWithSpaces(member.Size),
+		member.Format,
+	))
+	description.Alignment = fyne.TextAlignCenter
+	description.Wrapping = fyne.TextWrapBreak
+
+	p.compareChecks[index] = widget.NewCheck("Compare", func(checked bool) {
+		p.setCompared(index, checked)
+	})
+	p.deleteChecks[index] = widget.NewCheck("Delete", nil)
+
+	return container.NewVBox(
+		container.NewCenter(thumbnail),
+		description,
+		container.NewCenter(container.NewHBox(p.compareChecks[index], p.deleteChecks[index])),
+	)
+}
+
+// setCompared updates the members picked for comparison.
+// Picking a third member drops the oldest pick, and a complete pick is passed on to onCompare.
+func (p *GroupPanel) setCompared(index int, checked bool) {
+	for i, picked := range p.compared {
+		if picked == index {
+			p.compared = append(p.compared[:i], p.compared[i+1:]...)
+			break
+		}
+	}
+	if !checked {
+		return
+	}
+	p.compared = append(p.compared, index)
+	if len(p.compared) > 2 {
+		oldest := p.compared[0]
+		p.compared = p.compared[1:]
+		p.compareChecks[oldest].SetChecked(false)
+	}
+	if len(p.compared) == 2 {
+		p.onCompare(p.compared[0], p.compared[1])
+	}
+}
+
+// Marked returns the indices of the members marked for deletion.
+func (p *GroupPanel) Marked() []int {
+	var marked []int
+	for i, check := range p.deleteChecks {
+		if check.Checked {
+			marked = append(marked, i)
+		}
+	}
+	return marked
+}
+
+// Member returns the member at the given index of the displayed group.
+func (p *GroupPanel) Member(index int) GroupMember {
+	return p.members[index]
+}
+
+func (p *GroupPanel) GetContainer() *fyne.Container {
+	return p.container
+}
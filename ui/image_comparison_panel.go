@@ -3,21 +3,34 @@ package ui
 import (
 	"fmt"
 	"image"
+	"image/color"
 	"imgcomp/ui/custom"
 	"imgcomp/util"
+	"strings"
 
 	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/canvas"
 	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/theme"
 	"fyne.io/fyne/v2/widget"
 )
 
 type ImageComparisonPanel struct {
 	container *fyne.Container
 
-	image1Canvas *custom.ClickableImage
+	image1Canvas *custom.ZoomableImage
 	image1Label  *widget.RichText
-	image2Canvas *custom.ClickableImage
+	image2Canvas *custom.ZoomableImage
 	image2Label  *widget.RichText
+
+	zoomGroup *custom.ZoomGroup
+	zoomLabel *widget.Label
+
+	hashLabel        *widget.Label
+	orientationLabel *widget.Label
+
+	suggestionLabel *widget.Label
+	keeperHighlight [2]*canvas.Rectangle
 }
 
 func (p *ImageComparisonPanel) Image1Container() fyne.CanvasObject {
@@ -45,6 +58,7 @@ func (p *ImageComparisonPanel) SetImage(imageNumber int, img *image.Image, path
 		p.image2Canvas.SetImage(*img)
 		chosenLabel = p.image2Label
 	}
+	p.updateZoomLabel()
 	chosenLabel.ParseMarkdown(formattedString)
 	for i := range chosenLabel.Segments {
 		if seg, ok := chosenLabel.Segments[i].(*widget.TextSegment); ok {
@@ -54,9 +68,76 @@ func (p *ImageComparisonPanel) SetImage(imageNumber int, img *image.Image, path
 	}
 }
 
+// ZoomGroup returns the viewport shared by both images, other views join it to show the same region.
+func (p *ImageComparisonPanel) ZoomGroup() *custom.ZoomGroup {
+	return p.zoomGroup
+}
+
+// SetAlignment makes the shared viewport follow the region of both images that is compared,
+// so that views of the aligned images, such as the difference, show the same spot.
+func (p *ImageComparisonPanel) SetAlignment(alignment util.Alignment) {
+	p.image1Canvas.SetRegion(alignment.FromImage1)
+	p.image2Canvas.SetRegion(alignment.FromImage2)
+}
+
+// SetOnHover reports which image (1 or 2) is under the mouse and the position on it,
+// as a fraction of the image width and height.
+func (p *ImageComparisonPanel) SetOnHover(onHover func(imageNumber int, x, y float64), onHoverEnd func()) {
+	for i, img := range []*custom.ZoomableImage{p.image1Canvas, p.image2Canvas} {
+		imageNumber := i + 1
+		img.OnHover = func(x, y float64) {
+			onHover(imageNumber, x, y)
+		}
+		img.OnHoverEnd = onHoverEnd
+	}
+}
+
+// updateZoomLabel shows the zoom relative to the actual pixels of image 1.
+func (p *ImageComparisonPanel) updateZoomLabel() {
+	scale := p.image1Canvas.Scale()
+	if scale == 0 {
+		p.zoomLabel.SetText("")
+		return
+	}
+	p.zoomLabel.SetText(fmt.Sprintf("Zoom %.0f%%", scale*100))
+}
+
+// SetHashDistances shows how far apart the two images are in perceptual hash space.
+func (p *ImageComparisonPanel) SetHashDistances(distances []util.HashDistance) {
+	parts := make([]string, 0, len(distances))
+	for _, d := range distances {
+		parts = append(parts, fmt.Sprintf("%s: %d/%d", d.Algo, d.Distance, d.BitCount))
+	}
+	p.hashLabel.SetText("Hash distance | " + strings.Join(parts, " | "))
+}
+
+// SetOrientationWarning shows a warning about mismatching EXIF orientation, or hides it if the message is empty.
+func (p *ImageComparisonPanel) SetOrientationWarning(message string) {
+	p.orientationLabel.SetText(message)
+	if message == "" {
+		p.orientationLabel.Hide()
+	} else {
+		p.orientationLabel.Show()
+	}
+}
+
+// SetSuggestion highlights the suggested keeper (1 or 2, 0 for none) and explains the reasoning.
+func (p *ImageComparisonPanel) SetSuggestion(keep int, explanation string) {
+	p.suggestionLabel.SetText(explanation)
+	p.suggestionLabel.Show()
+	for i, highlight := range p.keeperHighlight {
+		if i+1 == keep {
+			highlight.Show()
+		} else {
+			highlight.Hide()
+		}
+	}
+}
+
 func NewImageComparisonPanel(
 	onImageClicked func(imageNumber int),
 	onImageDeleted func(imageNumber int),
+	onImageReplaced func(imageNumber int),
 	onImageIgnored func(),
 	algo util.ScalingAlgorithm,
 	showManagementButtons bool,
@@ -68,24 +149,47 @@ func NewImageComparisonPanel(
 	panel.image2Label = widget.NewRichTextFromMarkdown("Image 2")
 	panel.image2Label.Wrapping = fyne.TextWrap(fyne.TextAlignCenter)
 
-	panel.image1Canvas = custom.NewClickableImage(nil, func() {
+	panel.image1Canvas = custom.NewZoomableImage(func() {
 		onImageClicked(1)
 	}, algo)
-	panel.image1Canvas.SetImageMinSize(fyne.NewSize(util.ImageMaxWidth, util.ImageMaxHeight))
+	panel.image1Canvas.SetMinSize(fyne.NewSize(util.ImageMaxWidth, util.ImageMaxHeight))
 
-	panel.image2Canvas = custom.NewClickableImage(nil, func() {
+	panel.image2Canvas = custom.NewZoomableImage(func() {
 		onImageClicked(2)
 	}, algo)
-	panel.image2Canvas.SetImageMinSize(fyne.NewSize(util.ImageMaxWidth, util.ImageMaxHeight))
+	panel.image2Canvas.SetMinSize(fyne.NewSize(util.ImageMaxWidth, util.ImageMaxHeight))
+
+	// Zooming or panning either image moves the other one too
+	panel.zoomLabel = widget.NewLabel("")
+	panel.zoomGroup = custom.NewZoomGroup()
+	panel.zoomGroup.OnChanged = panel.updateZoomLabel
+	panel.zoomGroup.Add(panel.image1Canvas, panel.image2Canvas)
+	zoomRow := container.NewHBox(
+		widget.NewButton("Fit", panel.zoomGroup.Reset),
+		widget.NewButton("1:1", panel.image1Canvas.ActualSize),
+		panel.zoomLabel,
+		widget.NewLabel("Scroll to zoom, drag to pan, double click for actual size"),
+	)
+
+	// Outlines drawn around the suggested keeper
+	for i := range panel.keeperHighlight {
+		highlight := canvas.NewRectangle(color.Transparent)
+		highlight.StrokeColor = theme.Color(theme.ColorNameSuccess)
+		highlight.StrokeWidth = 4
+		highlight.Hide()
+		panel.keeperHighlight[i] = highlight
+	}
 
 	// Stack the canvas and the clickable button to make the canvas area interactive.
 	img1Container := container.NewStack(
 		container.NewCenter(widget.NewLabel("Drag an image to view it")),
 		panel.image1Canvas,
+		panel.keeperHighlight[0],
 	)
 	img2Container := container.NewStack(
 		container.NewCenter(widget.NewLabel("Drag an image to view it")),
 		panel.image2Canvas,
+		panel.keeperHighlight[1],
 	)
 
 	text1VBox := container.NewVBox(
@@ -99,18 +203,28 @@ func NewImageComparisonPanel(
 		img1Container,
 	)
 	if showManagementButtons {
-		img1VBox.Add(widget.NewButton("Delete", func() {
-			onImageDeleted(1)
-		}))
+		img1VBox.Add(container.NewGridWithColumns(2,
+			widget.NewButton("Delete", func() {
+				onImageDeleted(1)
+			}),
+			widget.NewButton("Replace with other", func() {
+				onImageReplaced(1)
+			}),
+		))
 	}
 
 	img2VBox := container.NewVBox(
 		img2Container,
 	)
 	if showManagementButtons {
-		img2VBox.Add(widget.NewButton("Delete", func() {
-			onImageDeleted(2)
-		}))
+		img2VBox.Add(container.NewGridWithColumns(2,
+			widget.NewButton("Delete", func() {
+				onImageDeleted(2)
+			}),
+			widget.NewButton("Replace with other", func() {
+				onImageReplaced(2)
+			}),
+		))
 	}
 
 	textRow := container.NewGridWithColumns(2,
@@ -123,7 +237,22 @@ func NewImageComparisonPanel(
 		img2VBox,
 	)
 
-	panel.container = container.NewVBox(textRow, imageRow)
+	panel.hashLabel = widget.NewLabel("")
+	panel.hashLabel.Alignment = fyne.TextAlignCenter
+
+	panel.orientationLabel = widget.NewLabel("")
+	panel.orientationLabel.Alignment = fyne.TextAlignCenter
+	panel.orientationLabel.Importance = widget.WarningImportance
+	panel.orientationLabel.Wrapping = fyne.TextWrapWord
+	panel.orientationLabel.Hide()
+
+	panel.suggestionLabel = widget.NewLabel("")
+	panel.suggestionLabel.Alignment = fyne.TextAlignCenter
+	panel.suggestionLabel.Importance = widget.SuccessImportance
+	panel.suggestionLabel.Wrapping = fyne.TextWrapWord
+	panel.suggestionLabel.Hide()
+
+	panel.container = container.NewVBox(textRow, panel.hashLabel, panel.orientationLabel, panel.suggestionLabel, imageRow, container.NewCenter(zoomRow))
 
 	if showManagementButtons {
 		ignoreButton := widget.NewButton("Ignore", onImageIgnored)
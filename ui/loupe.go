@@ -0,0 +1,157 @@
+package ui
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"math"
+	"strconv"
+	"strings"
+	"sync"
+
+	"imgcomp/util"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/canvas"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+)
+
+const loupeViewSize = 160 // Width and height of each magnified view
+
+// Magnifications offered by the loupe, in screen pixels per image pixel.
+var loupeMagnifications = []string{"2x", "4x", "8x", "16x", "32x"}
+
+// Loupe magnifies the same spot of image 1, image 2 and their difference at full resolution,
+// with the pixel values under the cursor.
+type Loupe struct {
+	container     *fyne.Container
+	viewsRow      *fyne.Container
+	sourcesMutex  sync.Mutex // SetImage runs while a pair is rendered, ShowAt from hover callbacks
+	sources       [3]image.Image
+	regions       [3]image.Rectangle // Parts of the sources that ShowAt positions are relative to
+	views         [3]*canvas.Image
+	markers       [3]*canvas.Rectangle
+	readouts      [3]*widget.Label
+	magnification int
+	enabled       bool
+}
+
+func NewLoupe() *Loupe {
+	l := &Loupe{magnification: 8}
+
+	columns := make([]fyne.CanvasObject, len(l.views))
+	for i, title := range []string{"Image 1", "Image 2", "Difference"} {
+		view := canvas.NewImageFromImage(nil)
+		view.FillMode = canvas.ImageFillContain
+		view.ScaleMode = canvas.ImageScalePixels
+		view.SetMinSize(fyne.NewSize(loupeViewSize, loupeViewSize))
+		l.views[i] = view
+
+		// Outline of the pixel under the cursor
+		marker := canvas.NewRectangle(color.Transparent)
+		marker.StrokeColor = theme.Color(theme.ColorNamePrimary)
+		marker.StrokeWidth = 1
+		l.markers[i] = marker
+
+		readout := widget.NewLabel("-")
+		readout.TextStyle = fyne.TextStyle{Monospace: true}
+		l.readouts[i] = readout
+
+		columns[i] = container.NewVBox(
+			widget.NewLabel(title),
+			container.NewStack(view, container.NewCenter(marker)),
+			readout,
+		)
+	}
+	l.viewsRow = container.NewHBox(columns...)
+	l.viewsRow.Hide()
+
+	magnificationSelect := widget.NewSelect(loupeMagnifications, func(selected string) {
+		if n, err := strconv.Atoi(strings.TrimSuffix(selected, "x")); err == nil {
+			l.magnification = n
+		}
+	})
+	magnificationSelect.SetSelected(fmt.Sprintf("%dx", l.magnification))
+
+	enableCheck := widget.NewCheck("Loupe", func(checked bool) {
+		l.enabled = checked
+		if checked {
+			l.viewsRow.Show()
+		} else {
+			l.viewsRow.Hide()
+		}
+	})
+
+	l.container = container.NewVBox(
+		container.NewHBox(enableCheck, magnificationSelect, widget.NewLabel("Hover over an image to magnify it")),
+		l.viewsRow,
+	)
+	return l
+}
+
+// SetImage sets the full resolution image sampled by a view: 0 for image 1, 1 for image 2 and 2 for the difference.
+// Positions passed to ShowAt are fractions of region, which lets the views follow how the images were aligned.
+func (l *Loupe) SetImage(index int, img image.Image, region image.Rectangle) {
+	l.sourcesMutex.Lock()
+	defer l.sourcesMutex.Unlock()
+	l.sources[index] = img
+	l.regions[index] = region
+}
+
+// ShowAt magnifies the spot at the given fraction of the width and height of the region of each image.
+// Fractions outside 0-1 are allowed, for spots outside the region the images have in common.
+func (l *Loupe) ShowAt(x, y float64) {
+	if !l.enabled {
+		return
+	}
+	// An odd number of pixels keeps the pixel under the cursor in the middle
+	size := loupeViewSize / l.magnification
+	if size%2 == 0 {
+		size++
+	}
+	pixelSize := float32(loupeViewSize) / float32(size)
+
+	l.sourcesMutex.Lock()
+	sources, regions := l.sources, l.regions
+	l.sourcesMutex.Unlock()
+	for i, src := range sources {
+		if src == nil {
+			l.clearView(i)
+			continue
+		}
+		bounds, region := src.Bounds(), regions[i]
+		px := int(math.Floor(float64(region.Min.X-bounds.Min.X) + x*float64(region.Dx())))
+		py := int(math.Floor(float64(region.Min.Y-bounds.Min.Y) + y*float64(region.Dy())))
+		l.views[i].Image = util.CropAround(src, px, py, size)
+		l.views[i].Refresh()
+		l.markers[i].SetMinSize(fyne.NewSize(pixelSize, pixelSize))
+		l.markers[i].Show()
+
+		if px < 0 || py < 0 || px >= bounds.Dx() || py >= bounds.Dy() {
+			l.readouts[i].SetText(fmt.Sprintf("%d, %d\noutside\nthe image", px, py))
+			continue
+		}
+		c := color.NRGBAModel.Convert(src.At(bounds.Min.X+px, bounds.Min.Y+py)).(color.NRGBA)
+		l.readouts[i].SetText(fmt.Sprintf("%d, %d\nR %3d G %3d\nB %3d A %3d", px, py, c.R, c.G, c.B, c.A))
+	}
+}
+
+// Clear empties the views once the cursor leaves the images.
+func (l *Loupe) Clear() {
+	for i := range l.views {
+		l.clearView(i)
+	}
+}
+
+func (l *Loupe) clearView(i int) {
+	l.views[i].Image = nil
+	l.views[i].Refresh()
+	l.markers[i].Hide()
+	l.readouts[i].SetText("-")
+}
+
+func (l *Loupe) GetContainer() *fyne.Container {
+	return l.container
+}
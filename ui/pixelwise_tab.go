@@ -1,38 +1,60 @@
 package ui
 
 import (
+	"fmt"
 	"image"
+	"imgcomp/ui/custom"
 	"imgcomp/util"
+	"math"
 
 	"fyne.io/fyne/v2"
-	"fyne.io/fyne/v2/canvas"
 	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/layout"
 	"fyne.io/fyne/v2/widget"
 )
 
+// Rows of the statistics table, in display order.
+var statRows = []string{"MAE", "MAE (R / G / B)", "Alpha MAE", "RMSE", "PSNR", "Max error", "Over threshold", "SSIM / MS-SSIM"}
+
 type PixelWiseTab struct {
 	resultLabel *widget.Label
-	diffCanvas  *canvas.Image
+	noteLabel   *widget.Label
+	statsValues map[string]*widget.Label
+	diffCanvas  *custom.ZoomableImage
 	container   *fyne.Container
 
 	showMonochrome     bool
 	onMonochromeChange func(bool)
+
+	showSSIMMap     bool
+	onSSIMMapChange func(bool)
 }
 
-func NewPixelWiseTab(algo util.ScalingAlgorithm, onMonochromeChange func(bool)) *PixelWiseTab {
+func NewPixelWiseTab(algo util.ScalingAlgorithm, onMonochromeChange func(bool), onSSIMMapChange func(bool)) *PixelWiseTab {
 	p := &PixelWiseTab{}
 	p.resultLabel = widget.NewLabel("???")
+	p.resultLabel.TextStyle = fyne.TextStyle{Bold: true}
+	p.noteLabel = widget.NewLabel("")
+	p.noteLabel.Importance = widget.WarningImportance
+	p.noteLabel.Hide()
+
+	statsTable := container.New(layout.NewFormLayout())
+	p.statsValues = make(map[string]*widget.Label, len(statRows))
+	for _, row := range statRows {
+		value := widget.NewLabel("-")
+		p.statsValues[row] = value
+		statsTable.Add(widget.NewLabel(row))
+		statsTable.Add(value)
+	}
+
 	p.showMonochrome = false
 	p.onMonochromeChange = onMonochromeChange
+	p.showSSIMMap = false
+	p.onSSIMMapChange = onSSIMMapChange
 
-	p.diffCanvas = canvas.NewImageFromImage(nil)
-	if algo == util.NearestNeighbor {
-		p.diffCanvas.ScaleMode = canvas.ImageScalePixels
-	} else {
-		p.diffCanvas.ScaleMode = canvas.ImageScaleFastest
-	}
+	p.diffCanvas = custom.NewZoomableImage(nil, algo)
+	p.diffCanvas.SetMaxPooling(true)
 	p.diffCanvas.SetMinSize(fyne.NewSize(util.ImageMaxWidth, util.ImageMaxHeight))
-	p.diffCanvas.FillMode = canvas.ImageFillContain
 
 	button := widget.NewButton("Toggle Monochrome Diff", func() {
 		p.showMonochrome = !p.showMonochrome
@@ -41,23 +63,84 @@ func NewPixelWiseTab(algo util.ScalingAlgorithm, onMonochromeChange func(bool))
 		}
 	})
 
+	ssimButton := widget.NewButton("Toggle SSIM Map", func() {
+		p.showSSIMMap = !p.showSSIMMap
+		if p.onSSIMMapChange != nil {
+			p.onSSIMMapChange(p.showSSIMMap)
+		}
+	})
+
 	p.container = container.NewVBox(
-		p.resultLabel, p.diffCanvas, button,
+		p.resultLabel, p.noteLabel, statsTable, p.diffCanvas, container.NewGridWithColumns(2, button, ssimButton),
 	)
 	return p
 }
 
 func (p *PixelWiseTab) SetImage(img *image.Image) {
-	p.diffCanvas.Image = *img
-	p.diffCanvas.Refresh()
+	p.diffCanvas.SetImage(*img)
+}
+
+// LinkZoom makes the difference image follow the zoom and position of the compared images.
+func (p *PixelWiseTab) LinkZoom(group *custom.ZoomGroup) {
+	group.Add(p.diffCanvas)
+}
+
+// SetOnHover reports the position under the mouse on the difference image, as a fraction of its width and height.
+func (p *PixelWiseTab) SetOnHover(onHover func(x, y float64), onHoverEnd func()) {
+	p.diffCanvas.OnHover = onHover
+	p.diffCanvas.OnHoverEnd = onHoverEnd
 }
 
 func (p *PixelWiseTab) ShowMonochrome() bool {
 	return p.showMonochrome
 }
 
-func (p *PixelWiseTab) SetMessage(message string) {
-	p.resultLabel.SetText(message)
+// ShowSSIMMap reports whether the SSIM map should be displayed instead of the pixel difference.
+func (p *PixelWiseTab) ShowSSIMMap() bool {
+	return p.showSSIMMap
+}
+
+// SetStats fills the statistics table with the result of a comparison.
+func (p *PixelWiseTab) SetStats(stats util.DiffStats, ssim, msssim float64) {
+	if stats.Identical() {
+		p.resultLabel.SetText("Images are identical")
+	} else {
+		p.resultLabel.SetText("Images differ")
+	}
+
+	psnr := "∞ dB"
+	if !math.IsInf(stats.PSNR, 1) {
+		psnr = fmt.Sprintf("%.2f dB", stats.PSNR)
+	}
+
+	p.statsValues["MAE"].SetText(fmt.Sprintf("%.2f (%d px)", stats.MAE, stats.PixelCount))
+	p.statsValues["MAE (R / G / B)"].SetText(fmt.Sprintf("%.2f / %.2f / %.2f", stats.ChannelMAE[0], stats.ChannelMAE[1], stats.ChannelMAE[2]))
+	p.statsValues["Alpha MAE"].SetText(fmt.Sprintf("%.2f", stats.AlphaMAE))
+	p.statsValues["RMSE"].SetText(fmt.Sprintf("%.2f", stats.RMSE))
+	p.statsValues["PSNR"].SetText(psnr)
+	p.statsValues["Max error"].SetText(fmt.Sprintf("%d", stats.MaxError))
+	p.statsValues["Over threshold"].SetText(fmt.Sprintf(
+		"%s px (%.2f%%) differ by more than %d",
+		util.FormatIntWithSpaces(int64(stats.DifferingPixels)),
+		stats.DifferingPercent(),
+		stats.Threshold,
+	))
+	p.statsValues["SSIM / MS-SSIM"].SetText(fmt.Sprintf("%.4f / %.4f", ssim, msssim))
+}
+
+// AddFrameScrubber places a frame scrubber below the difference image.
+func (p *PixelWiseTab) AddFrameScrubber(scrubber *FrameScrubber) {
+	p.container.Add(scrubber.GetContainer())
+}
+
+// SetNote shows an explanation of how the images were aligned before comparing, or hides it if empty.
+func (p *PixelWiseTab) SetNote(note string) {
+	p.noteLabel.SetText(note)
+	if note == "" {
+		p.noteLabel.Hide()
+	} else {
+		p.noteLabel.Show()
+	}
 }
 
 func (p *PixelWiseTab) GetContainer() *fyne.Container {
@@ -0,0 +1,61 @@
+package ui
+
+import (
+	"fmt"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/widget"
+)
+
+// QueueNavigator moves through a queue of image pairs or groups and shows how far along the review is.
+// It stays hidden until a queue is set.
+type QueueNavigator struct {
+	container      *fyne.Container
+	progressLabel  *widget.Label
+	progressBar    *widget.ProgressBar
+	previousButton *widget.Button
+	nextButton     *widget.Button
+	itemName       string
+}
+
+// NewQueueNavigator creates a navigator labelling the queued items with itemName, e.g. "Pair".
+func NewQueueNavigator(itemName string, onPrevious func(), onNext func()) *QueueNavigator {
+	n := &QueueNavigator{itemName: itemName}
+	n.progressLabel = widget.NewLabel("")
+	n.progressBar = widget.NewProgressBar()
+	n.progressBar.TextFormatter = func() string { return "" }
+	n.previousButton = widget.NewButton("Previous", onPrevious)
+	n.nextButton = widget.NewButton("Next", onNext)
+
+	n.container = container.NewBorder(nil, nil,
+		n.previousButton,
+		container.NewHBox(n.progressLabel, n.nextButton),
+		n.progressBar,
+	)
+	n.container.Hide()
+	return n
+}
+
+// SetProgress shows the position of the current item within the queue.
+// The index is zero-based.
+func (n *QueueNavigator) SetProgress(index, total int) {
+	n.progressLabel.SetText(fmt.Sprintf("%s %d / %d", n.itemName, index+1, total))
+	n.progressBar.SetValue(float64(index+1) / float64(max(total, 1)))
+
+	if index <= 0 {
+		n.previousButton.Disable()
+	} else {
+		n.previousButton.Enable()
+	}
+	if index >= total-1 {
+		n.nextButton.Disable()
+	} else {
+		n.nextButton.Enable()
+	}
+	n.container.Show()
+}
+
+func (n *QueueNavigator) GetContainer() *fyne.Container {
+	return n.container
+}
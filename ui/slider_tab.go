@@ -1,80 +1,102 @@
 package ui
 
 import (
-	"fmt"
 	"image"
+	"imgcomp/ui/custom"
 	"imgcomp/util"
 	"sync"
 	"time"
 
 	"fyne.io/fyne/v2"
-	"fyne.io/fyne/v2/canvas"
 	"fyne.io/fyne/v2/container"
 	"fyne.io/fyne/v2/widget"
 )
 
+// layerMode is what the layer slider does with image 2: wipe it over image 1 or blend it with image 1.
+type layerMode struct {
+	name  string
+	wipe  bool
+	blend util.BlendMode
+}
+
+var layerModes = []layerMode{
+	{name: "Wipe", wipe: true},
+	{name: "Onion skin", blend: util.BlendNormal},
+	{name: "Difference", blend: util.BlendDifference},
+	{name: "Multiply", blend: util.BlendMultiply},
+	{name: "Screen", blend: util.BlendScreen},
+}
+
+// Ways the wipe reveals image 2, in the order they are offered.
+var wipeModes = []struct {
+	name string
+	mode custom.WipeMode
+}{
+	{"Left / right", custom.WipeHorizontal},
+	{"Top / bottom", custom.WipeVertical},
+	{"Spotlight", custom.WipeSpotlight},
+}
+
 type LayerSliderTab struct {
-	imageContainer *fyne.Container
-	container      *fyne.Container
-	img1           *image.Image
-	img2           *image.Image
-	scalingAlgo    util.ScalingAlgorithm
+	view        *custom.WipeView
+	slider      *widget.Slider
+	container   *fyne.Container
+	resized1    image.Image
+	resized2    image.Image
+	scalingAlgo util.ScalingAlgorithm
+	mode        layerMode
+	wipeMode    custom.WipeMode
+	value       float64
 }
 
 func (s *LayerSliderTab) RemoveAll() *fyne.Container {
-	s.imageContainer.RemoveAll()
+	s.resized1, s.resized2 = nil, nil
+	s.view.SetImages(nil, nil)
 	return s.container
 }
 
 func (s *LayerSliderTab) Refresh() {
-	s.imageContainer.Refresh()
+	s.view.Refresh()
 	s.container.Refresh()
 }
 
 func (s *LayerSliderTab) Compare(img1, img2 *image.Image, algo util.ScalingAlgorithm) {
-
-	s.img1 = img1
-	s.img2 = img2
 	s.scalingAlgo = algo
 
 	sizeX, sizeY := util.GetScaledBounds(img1)
-	newSize := fyne.NewSize(sizeX, sizeY)
+	s.view.SetMinSize(fyne.NewSize(sizeX, sizeY))
 
-	resized1 := util.RescaleImageFast(*img1, algo)
-	resized2 := util.RescaleImageFast(*img2, algo)
+	s.resized1 = util.RescaleImageFast(*img1, algo)
+	s.resized2 = util.RescaleImageFast(*img2, algo)
+	s.update()
+	s.Refresh()
+}
 
-	comp1 := canvas.NewImageFromImage(resized1)
-	comp1.FillMode = canvas.ImageFillOriginal
-	if algo == util.NearestNeighbor {
-		comp1.ScaleMode = canvas.ImageScalePixels
-	} else {
-		comp1.ScaleMode = canvas.ImageScaleFastest
+// update redraws the view for the current mode and slider value: the slider is the position of the divider,
+// the radius of the spotlight, or the opacity of image 2 in the blend modes.
+func (s *LayerSliderTab) update() {
+	if s.resized1 == nil {
+		return
 	}
-	comp1.SetMinSize(newSize)
-	comp1.Resize(newSize)
-	comp1.Move(fyne.NewPos(0, 0))
-
-	cropped := util.CropImageFast(&resized2, 0.5, algo)
-	comp2 := canvas.NewImageFromImage(cropped)
-	comp2.FillMode = canvas.ImageFillOriginal
-	if algo == util.NearestNeighbor {
-		comp2.ScaleMode = canvas.ImageScalePixels
+	if !s.mode.wipe {
+		s.view.SetImages(util.BlendImages(s.resized1, s.resized2, s.mode.blend, s.value), nil)
+		return
+	}
+	s.view.SetMode(s.wipeMode)
+	if s.wipeMode == custom.WipeSpotlight {
+		s.view.SetSpotlightRadius(s.value / 2)
 	} else {
-		comp2.ScaleMode = canvas.ImageScaleFastest
+		s.view.SetPosition(s.value)
 	}
-	comp2.SetMinSize(newSize)
-	comp2.Resize(newSize)
-	comp2.Move(fyne.NewPos(0, 0))
-
-	s.imageContainer.Add(comp1)
-	s.imageContainer.Add(comp2)
-	s.Refresh()
+	s.view.SetImages(s.resized1, s.resized2)
 }
 
 func NewLayerSliderTab(algo util.ScalingAlgorithm) *LayerSliderTab {
 	sliderSection := &LayerSliderTab{
-		imageContainer: container.NewWithoutLayout(),
-		scalingAlgo:    algo,
+		scalingAlgo: algo,
+		mode:        layerModes[0],
+		wipeMode:    wipeModes[0].mode,
+		value:       0.5,
 	}
 
 	var debounceTimer *time.Timer
@@ -83,36 +105,62 @@ func NewLayerSliderTab(algo util.ScalingAlgorithm) *LayerSliderTab {
 	slider.Step = 0.01
 	slider.Value = 0.5 // start in the middle
 	slider.OnChanged = func(val float64) {
-		if len(sliderSection.imageContainer.Objects) < 2 {
-			fmt.Println("Slider image container does not have enough objects to update")
-			return
-		}
-
 		debounceMutex.Lock()
 		if debounceTimer != nil {
 			debounceTimer.Stop()
 		}
 		debounceTimer = time.AfterFunc(10*time.Millisecond, func() {
-
-			_, ok := sliderSection.imageContainer.Objects[1].(*canvas.Image)
-			if !ok {
-				fmt.Println("Slider image container does not contain a canvas.Image at index 1")
-				return
-			}
-			cropped := util.CropImageFast(sliderSection.img2, val, sliderSection.scalingAlgo)
-
-			sliderSection.imageContainer.Objects[1].(*canvas.Image).Image = cropped
 			fyne.Do(func() {
-				canvas.Refresh(sliderSection.imageContainer.Objects[1])
+				sliderSection.value = val
+				sliderSection.update()
 			})
-
-			//sliderImageContainer.Objects[1].(*canvas.Image).Refresh()
 		})
 		debounceMutex.Unlock()
 	}
+	sliderSection.slider = slider
+
+	// Dragging the divider on the image moves the slider along
+	sliderSection.view = custom.NewWipeView("Image 1", "Image 2", func(position float64) {
+		sliderSection.value = position
+		slider.SetValue(position)
+	}, algo)
+
+	wipeNames := make([]string, len(wipeModes))
+	for i, mode := range wipeModes {
+		wipeNames[i] = mode.name
+	}
+	wipeSelect := widget.NewSelect(wipeNames, func(selected string) {
+		for _, mode := range wipeModes {
+			if mode.name == selected {
+				sliderSection.wipeMode = mode.mode
+			}
+		}
+		sliderSection.update()
+	})
+	wipeSelect.SetSelected(wipeModes[0].name)
+
+	modeNames := make([]string, len(layerModes))
+	for i, mode := range layerModes {
+		modeNames[i] = mode.name
+	}
+	modeSelect := widget.NewSelect(modeNames, func(selected string) {
+		for _, mode := range layerModes {
+			if mode.name == selected {
+				sliderSection.mode = mode
+			}
+		}
+		if sliderSection.mode.wipe {
+			wipeSelect.Enable()
+		} else {
+			wipeSelect.Disable()
+		}
+		sliderSection.update()
+	})
+	modeSelect.SetSelected(layerModes[0].name)
+
 	sliderSection2 := container.NewVBox(
-		widget.NewLabel("Slider Section"),
-		sliderSection.imageContainer,
+		container.NewHBox(widget.NewLabel("Slider Section"), modeSelect, wipeSelect),
+		container.NewCenter(sliderSection.view),
 		slider,
 	)
 
@@ -121,6 +169,11 @@ func NewLayerSliderTab(algo util.ScalingAlgorithm) *LayerSliderTab {
 	return sliderSection
 }
 
+// AddFrameScrubber places a frame scrubber below the slider.
+func (s *LayerSliderTab) AddFrameScrubber(scrubber *FrameScrubber) {
+	s.container.Add(scrubber.GetContainer())
+}
+
 func (s *LayerSliderTab) GetContainer() *fyne.Container {
 	return s.container
 }
@@ -0,0 +1,64 @@
+package ui
+
+import (
+	"strconv"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/widget"
+)
+
+// UndoBar shows the last decision and undoes any number of the most recent ones.
+// It stays hidden until the first decision is made.
+type UndoBar struct {
+	container   *fyne.Container
+	statusLabel *widget.Label
+	countSelect *widget.Select
+	undoButton  *widget.Button
+}
+
+func NewUndoBar(onUndo func(n int)) *UndoBar {
+	b := &UndoBar{}
+	b.statusLabel = widget.NewLabel("")
+	b.statusLabel.Truncation = fyne.TextTruncateEllipsis
+	b.countSelect = widget.NewSelect(nil, nil)
+	b.undoButton = widget.NewButton("Undo", func() {
+		n, err := strconv.Atoi(b.countSelect.Selected)
+		if err != nil {
+			n = 1
+		}
+		onUndo(n)
+	})
+
+	b.container = container.NewBorder(nil, nil, nil,
+		container.NewHBox(widget.NewLabel("Last"), b.countSelect, b.undoButton),
+		b.statusLabel,
+	)
+	b.container.Hide()
+	return b
+}
+
+// SetState shows the description of the last decision and how many decisions can be undone.
+func (b *UndoBar) SetState(undoable int, last string) {
+	b.statusLabel.SetText(last)
+
+	options := make([]string, undoable)
+	for i := range options {
+		options[i] = strconv.Itoa(i + 1)
+	}
+	b.countSelect.SetOptions(options)
+	if undoable == 0 {
+		b.countSelect.ClearSelected()
+		b.countSelect.Disable()
+		b.undoButton.Disable()
+	} else {
+		b.countSelect.SetSelectedIndex(0)
+		b.countSelect.Enable()
+		b.undoButton.Enable()
+	}
+	b.container.Show()
+}
+
+func (b *UndoBar) GetContainer() *fyne.Container {
+	return b.container
+}
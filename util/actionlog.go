@@ -0,0 +1,155 @@
+package util
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// ActionKind is the kind of decision recorded in the action log.
+type ActionKind string
+
+const (
+	ActionKeep    ActionKind = "keep"
+	ActionDelete  ActionKind = "delete"
+	ActionIgnore  ActionKind = "ignore"
+	ActionReplace ActionKind = "replace"
+	ActionUndo    ActionKind = "undo"
+)
+
+// Action is a single entry of the action log.
+type Action struct {
+	Time        time.Time  `json:"time"`
+	Kind        ActionKind `json:"action"`
+	Path        string     `json:"path"`
+	OtherPath   string     `json:"other_path,omitempty"`  // Image the decision was made against
+	Destination string     `json:"destination,omitempty"` // Where a deleted or replaced file was moved to
+	Method      string     `json:"method,omitempty"`      // How a replaced file was linked to the kept one
+	Undoes      ActionKind `json:"undoes,omitempty"`      // Kind of the undone action, for ActionUndo
+	Rule        string     `json:"rule,omitempty"`        // Rule that made the decision, empty for manual decisions
+}
+
+// String describes the action in a few words.
+func (a Action) String() string {
+	switch a.Kind {
+	case ActionDelete:
+		return "deleted " + filepath.Base(a.Path)
+	case ActionKeep:
+		return "kept " + filepath.Base(a.Path)
+	case ActionReplace:
+		return fmt.Sprintf("replaced %s with a %s of %s", filepath.Base(a.Path), a.Method, filepath.Base(a.OtherPath))
+	case ActionIgnore:
+		return fmt.Sprintf("ignored %s and %s", filepath.Base(a.Path), filepath.Base(a.OtherPath))
+	case ActionUndo:
+		return fmt.Sprintf("undid %s of %s", a.Undoes, filepath.Base(a.Path))
+	}
+	return string(a.Kind)
+}
+
+// decision is a group of actions taken with a single click, undone together.
+type decision struct {
+	actions []Action
+	undo    func() error // nil if the decision cannot be undone
+}
+
+// ActionLog records the decisions of a session so that they can be undone,
+// and appends every action to an audit log as a JSON line.
+type ActionLog struct {
+	auditPath string
+	decisions []decision
+}
+
+// DefaultAuditLogPath returns the location of the audit log in the data directory.
+func DefaultAuditLogPath() (string, error) {
+	dataDir, err := DataDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dataDir, "audit.log"), nil
+}
+
+// NewActionLog creates an empty action log writing to the audit log at the given path.
+func NewActionLog(auditPath string) *ActionLog {
+	return &ActionLog{auditPath: auditPath}
+}
+
+// Record adds a decision made of one or more actions.
+// The undo function reverts all of them, or is nil if the decision cannot be undone.
+func (l *ActionLog) Record(undo func() error, actions ...Action) error {
+	now := time.Now()
+	for i := range actions {
+		actions[i].Time = now
+	}
+	l.decisions = append(l.decisions, decision{actions: actions, undo: undo})
+	return l.writeAudit(actions)
+}
+
+// Undoable returns how many of the most recent decisions can be undone in a row.
+func (l *ActionLog) Undoable() int {
+	count := 0
+	for i := len(l.decisions) - 1; i >= 0 && l.decisions[i].undo != nil; i-- {
+		count++
+	}
+	return count
+}
+
+// Last returns the actions of the most recent decision, or nil if there is none.
+func (l *ActionLog) Last() []Action {
+	if len(l.decisions) == 0 {
+		return nil
+	}
+	return l.decisions[len(l.decisions)-1].actions
+}
+
+// Undo reverts the last n decisions, most recent first, and returns the actions of each undone decision.
+// It stops at the first decision that cannot be undone or fails to undo.
+func (l *ActionLog) Undo(n int) ([][]Action, error) {
+	var undone [][]Action
+	for range n {
+		if len(l.decisions) == 0 {
+			break
+		}
+		last := l.decisions[len(l.decisions)-1]
+		if last.undo == nil {
+			return undone, fmt.Errorf("%s cannot be undone", last.actions[0])
+		}
+		if err := last.undo(); err != nil {
+			return undone, err
+		}
+		l.decisions = l.decisions[:len(l.decisions)-1]
+		undone = append(undone, last.actions)
+
+		reverts := make([]Action, len(last.actions))
+		for i, action := range last.actions {
+			reverts[i] = Action{Time: time.Now(), Kind: ActionUndo, Path: action.Path, OtherPath: action.OtherPath, Undoes: action.Kind}
+		}
+		if err := l.writeAudit(reverts); err != nil {
+			return undone, err
+		}
+	}
+	return undone, nil
+}
+
+// writeAudit appends actions to the audit log.
+func (l *ActionLog) writeAudit(actions []Action) error {
+	if l.auditPath == "" {
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Dir(l.auditPath), 0755); err != nil {
+		return err
+	}
+	file, err := os.OpenFile(l.auditPath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	encoder := json.NewEncoder(file)
+	for _, action := range actions {
+		if err := encoder.Encode(action); err != nil {
+			file.Close()
+			return err
+		}
+	}
+	return file.Close()
+}
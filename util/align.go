@@ -0,0 +1,61 @@
+package util
+
+import (
+	"image"
+
+	"github.com/disintegration/imaging"
+)
+
+// Alignment is how two images are brought into a common frame before comparing them:
+// image 2 is transformed into the orientation of image 1, then both are cut down to the regions they have in common.
+type Alignment struct {
+	Transform Transform       // Applied to image 2 to match the orientation of image 1
+	Bounds1   image.Rectangle // Bounds of image 1
+	Bounds2   image.Rectangle // Bounds of image 2 after the transform
+	Rect1     image.Rectangle // Compared region of image 1
+	Rect2     image.Rectangle // Compared region of the transformed image 2
+}
+
+// NewAlignment creates an alignment comparing the whole of both images, with image 2 already transformed.
+func NewAlignment(img1, aligned2 image.Image, transform Transform) Alignment {
+	return Alignment{
+		Transform: transform,
+		Bounds1:   img1.Bounds(),
+		Bounds2:   aligned2.Bounds(),
+		Rect1:     img1.Bounds(),
+		Rect2:     aligned2.Bounds(),
+	}
+}
+
+// Crop returns the compared regions of image 1 and of the transformed image 2, or the images themselves if they are compared whole.
+func (a Alignment) Crop(img1, aligned2 image.Image) (image.Image, image.Image) {
+	if a.Rect1 != img1.Bounds() {
+		img1 = imaging.Crop(img1, a.Rect1)
+	}
+	if a.Rect2 != aligned2.Bounds() {
+		aligned2 = imaging.Crop(aligned2, a.Rect2)
+	}
+	return img1, aligned2
+}
+
+// FromImage1 maps a position on image 1, as fractions of its width and height, to fractions of the compared region.
+func (a Alignment) FromImage1(x, y float64) (float64, float64) {
+	return toRegion(a.Bounds1, a.Rect1, x, y)
+}
+
+// FromImage2 maps a position on image 2 as it was loaded, as fractions of its width and height,
+// to fractions of the compared region.
+func (a Alignment) FromImage2(x, y float64) (float64, float64) {
+	x, y = a.Transform.MapFraction(x, y)
+	return toRegion(a.Bounds2, a.Rect2, x, y)
+}
+
+// toRegion converts fractions of bounds to fractions of a region inside it, which lie outside 0-1 beyond the region.
+func toRegion(bounds, region image.Rectangle, x, y float64) (float64, float64) {
+	if region.Empty() {
+		return x, y
+	}
+	px := float64(bounds.Min.X) + x*float64(bounds.Dx())
+	py := float64(bounds.Min.Y) + y*float64(bounds.Dy())
+	return (px - float64(region.Min.X)) / float64(region.Dx()), (py - float64(region.Min.Y)) / float64(region.Dy())
+}
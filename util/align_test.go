@@ -0,0 +1,44 @@
+package util
+
+import (
+	"image"
+	"math"
+	"math/rand"
+	"testing"
+
+	"github.com/disintegration/imaging"
+)
+
+func TestAlignmentMapsImage2ToTheSamePixelOfImage1(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	img1 := randomNRGBA(r, image.Rect(0, 0, 60, 40))
+	rect := image.Rect(10, 5, 50, 35)
+
+	for _, transform := range Transforms {
+		// Image 2 is a crop of image 1 with the transform applied
+		img2 := transform.Apply(imaging.Crop(img1, rect))
+		aligned2 := transform.Inverse().Apply(img2)
+		alignment := NewAlignment(img1, aligned2, transform.Inverse())
+		alignment.Rect1 = rect
+
+		b2 := img2.Bounds()
+		for y := b2.Min.Y; y < b2.Max.Y; y++ {
+			for x := b2.Min.X; x < b2.Max.X; x++ {
+				u, v := alignment.FromImage2((float64(x)+0.5)/float64(b2.Dx()), (float64(y)+0.5)/float64(b2.Dy()))
+				p := image.Pt(
+					rect.Min.X+int(math.Floor(u*float64(rect.Dx()))),
+					rect.Min.Y+int(math.Floor(v*float64(rect.Dy()))),
+				)
+				if img1.At(p.X, p.Y) != img2.At(x, y) {
+					t.Fatalf("%v: pixel (%d, %d) of image 2 mapped to %v of image 1, which differs", transform, x, y, p)
+				}
+			}
+		}
+
+		// The center of the crop in image 1 is the center of the compared region
+		u, v := alignment.FromImage1(float64(rect.Min.X+rect.Max.X)/2/60, float64(rect.Min.Y+rect.Max.Y)/2/40)
+		if math.Abs(u-0.5) > 1e-9 || math.Abs(v-0.5) > 1e-9 {
+			t.Errorf("%v: center of the crop mapped to %v, %v", transform, u, v)
+		}
+	}
+}
@@ -0,0 +1,198 @@
+package util
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/draw"
+	"image/gif"
+	"os"
+	"time"
+)
+
+// defaultFrameDelay is used for frames that do not specify a usable delay.
+const defaultFrameDelay = 100 * time.Millisecond
+
+// maxCanvasPixels is the largest animation canvas that is allocated. Canvas sizes come straight from
+// the file headers, and every frame is kept as a full copy of the canvas, so larger sizes are rejected.
+const maxCanvasPixels = 1 << 26
+
+// Frame is a single fully composited frame of an animation.
+type Frame struct {
+	Image image.Image
+	Delay time.Duration
+}
+
+// Animation holds the frames of an image. Still images have exactly one frame.
+type Animation struct {
+	Frames []Frame
+}
+
+// IsAnimated reports whether the animation has more than one frame.
+func (a *Animation) IsAnimated() bool {
+	return len(a.Frames) > 1
+}
+
+// Duration returns the total playback time of a single loop.
+func (a *Animation) Duration() time.Duration {
+	var total time.Duration
+	for _, f := range a.Frames {
+		total += f.Delay
+	}
+	return total
+}
+
+// Frame returns the frame at the given index, clamped to the available frames.
+func (a *Animation) Frame(index int) image.Image {
+	index = min(max(index, 0), len(a.Frames)-1)
+	return a.Frames[index].Image
+}
+
+// LoadAnimation loads every frame of an animated GIF, APNG or WebP file.
+// Other files, files of those formats without animation, and files the animation decoders reject,
+// such as truncated GIFs that the image decoder still accepts, are loaded through LoadImage as a single frame.
+func LoadAnimation(path string) (*Animation, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var anim *Animation
+	switch {
+	case bytes.HasPrefix(data, []byte("GIF8")):
+		anim, err = decodeGIFAnimation(data)
+	case bytes.HasPrefix(data, pngSignature):
+		anim, err = decodeAPNG(data)
+	case len(data) >= 12 && string(data[0:4]) == "RIFF" && string(data[8:12]) == "WEBP":
+		anim, err = decodeWebPAnimation(data)
+	}
+	if err == nil && anim != nil {
+		return anim, nil
+	}
+	animErr := err
+
+	img, err := LoadImage(path)
+	if err != nil {
+		if animErr != nil {
+			return nil, fmt.Errorf("failed to decode %s: %w", path, errors.Join(animErr, err))
+		}
+		return nil, err
+	}
+	return &Animation{Frames: []Frame{{Image: img}}}, nil
+}
+
+// checkCanvasSize rejects canvas sizes that are empty or exceed maxCanvasPixels before they are allocated.
+func checkCanvasSize(width, height int) error {
+	if width <= 0 || height <= 0 || width > maxCanvasPixels/height {
+		return fmt.Errorf("animation canvas %dx%d exceeds %d pixels", width, height, maxCanvasPixels)
+	}
+	return nil
+}
+
+// compositeFrame draws a frame onto the canvas at rect, either alpha-blending it or replacing the area.
+func compositeFrame(canvas *image.RGBA, frame image.Image, rect image.Rectangle, blend bool) {
+	op := draw.Src
+	if blend {
+		op = draw.Over
+	}
+	draw.Draw(canvas, rect, frame, frame.Bounds().Min, op)
+}
+
+// cloneRGBA returns a copy of the canvas, used both as frame output and for restoring previous frames.
+func cloneRGBA(src *image.RGBA) *image.RGBA {
+	dst := image.NewRGBA(src.Bounds())
+	copy(dst.Pix, src.Pix)
+	return dst
+}
+
+// clearRect makes the area of the canvas fully transparent.
+func clearRect(canvas *image.RGBA, rect image.Rectangle) {
+	draw.Draw(canvas, rect, image.Transparent, image.Point{}, draw.Src)
+}
+
+// decodeGIFAnimation composites all frames of a GIF. It returns nil for single-frame GIFs.
+func decodeGIFAnimation(data []byte) (*Animation, error) {
+	g, err := gif.DecodeAll(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	if len(g.Image) < 2 {
+		return nil, nil
+	}
+
+	if err := checkCanvasSize(g.Config.Width, g.Config.Height); err != nil {
+		return nil, err
+	}
+	canvas := image.NewRGBA(image.Rect(0, 0, g.Config.Width, g.Config.Height))
+	anim := &Animation{Frames: make([]Frame, 0, len(g.Image))}
+	for i, frame := range g.Image {
+		var previous *image.RGBA
+		if g.Disposal[i] == gif.DisposalPrevious {
+			previous = cloneRGBA(canvas)
+		}
+
+		compositeFrame(canvas, frame, frame.Bounds(), true)
+
+		delay := time.Duration(g.Delay[i]) * 10 * time.Millisecond
+		if delay <= 0 {
+			delay = defaultFrameDelay
+		}
+		anim.Frames = append(anim.Frames, Frame{Image: cloneRGBA(canvas), Delay: delay})
+
+		switch g.Disposal[i] {
+		case gif.DisposalBackground:
+			clearRect(canvas, frame.Bounds())
+		case gif.DisposalPrevious:
+			canvas = previous
+		}
+	}
+	return anim, nil
+}
+
+// AnimationComparison summarizes how two animations differ frame by frame.
+type AnimationComparison struct {
+	FrameCount1 int
+	FrameCount2 int
+	Duration1   time.Duration
+	Duration2   time.Duration
+	FrameMAE    []float64 // MAE of each frame pair, the shorter animation repeats its last frame
+}
+
+// Animated reports whether at least one of the compared images is animated.
+func (c AnimationComparison) Animated() bool {
+	return c.FrameCount1 > 1 || c.FrameCount2 > 1
+}
+
+// Summary describes the frame count and duration mismatches between the animations.
+func (c AnimationComparison) Summary() string {
+	summary := fmt.Sprintf("Frames: %d vs %d | Duration: %v vs %v", c.FrameCount1, c.FrameCount2, c.Duration1, c.Duration2)
+	switch {
+	case c.FrameCount1 != c.FrameCount2 && c.Duration1 != c.Duration2:
+		summary += " | frame count and duration differ"
+	case c.FrameCount1 != c.FrameCount2:
+		summary += " | frame count differs"
+	case c.Duration1 != c.Duration2:
+		summary += " | duration differs"
+	}
+	return summary
+}
+
+// CompareAnimations computes the MAE of every frame pair on rescaled copies of the frames.
+func CompareAnimations(anim1, anim2 *Animation, algo ScalingAlgorithm) AnimationComparison {
+	frameCount := max(len(anim1.Frames), len(anim2.Frames))
+	comparison := AnimationComparison{
+		FrameCount1: len(anim1.Frames),
+		FrameCount2: len(anim2.Frames),
+		Duration1:   anim1.Duration(),
+		Duration2:   anim2.Duration(),
+		FrameMAE:    make([]float64, frameCount),
+	}
+	for i := 0; i < frameCount; i++ {
+		frame1 := RescaleImageFast(anim1.Frame(i), algo)
+		frame2 := RescaleImageFast(anim2.Frame(i), algo)
+		_, stats := ComputeImageDiffFast(&frame1, &frame2, algo, false, DefaultDiffThreshold)
+		comparison.FrameMAE[i] = stats.MAE
+	}
+	return comparison
+}
@@ -0,0 +1,98 @@
+package util
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+	"image/color"
+	"image/color/palette"
+	"image/gif"
+	"image/png"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func writeTestFile(t *testing.T, name string, data []byte) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadAnimationFallsBackOnTruncatedGIF(t *testing.T) {
+	anim := &gif.GIF{}
+	for i := range 2 {
+		frame := image.NewPaletted(image.Rect(0, 0, 64, 64), palette.Plan9)
+		for j := range frame.Pix {
+			frame.Pix[j] = uint8(j*7 + i)
+		}
+		anim.Image = append(anim.Image, frame)
+		anim.Delay = append(anim.Delay, 10)
+	}
+	var buf bytes.Buffer
+	if err := gif.EncodeAll(&buf, anim); err != nil {
+		t.Fatal(err)
+	}
+	path := writeTestFile(t, "truncated.gif", buf.Bytes()[:buf.Len()-20])
+
+	if _, err := LoadImage(path); err != nil {
+		t.Fatalf("LoadImage rejects the truncated GIF, the test needs a cut it accepts: %v", err)
+	}
+	loaded, err := LoadAnimation(path)
+	if err != nil {
+		t.Fatalf("LoadAnimation: %v", err)
+	}
+	if len(loaded.Frames) != 1 || loaded.Frame(0).Bounds().Dx() != 64 {
+		t.Errorf("got %d frames, want the first frame only", len(loaded.Frames))
+	}
+}
+
+func TestLoadAnimationIgnoresDataAfterIEND(t *testing.T) {
+	img := image.NewNRGBA(image.Rect(0, 0, 8, 4))
+	img.Set(3, 2, color.NRGBA{R: 200, G: 100, B: 50, A: 255})
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatal(err)
+	}
+	buf.Write(bytes.Repeat([]byte{0xAB}, 40))
+	path := writeTestFile(t, "trailing.png", buf.Bytes())
+
+	loaded, err := LoadAnimation(path)
+	if err != nil {
+		t.Fatalf("LoadAnimation: %v", err)
+	}
+	if got := color.NRGBAModel.Convert(loaded.Frame(0).At(3, 2)).(color.NRGBA); got != (color.NRGBA{R: 200, G: 100, B: 50, A: 255}) {
+		t.Errorf("pixel (3, 2) = %v", got)
+	}
+}
+
+func TestDecodeWebPAnimationRejectsOversizedCanvas(t *testing.T) {
+	var chunks bytes.Buffer
+	vp8x := make([]byte, 10)
+	vp8x[0] = webpFlagAnimation
+	putUint24(vp8x[4:7], 0xFFFFFF)
+	putUint24(vp8x[7:10], 0xFFFFFF)
+	writeRIFFChunk(&chunks, "VP8X", vp8x)
+	writeRIFFChunk(&chunks, "ANIM", make([]byte, 6))
+	writeRIFFChunk(&chunks, "ANMF", make([]byte, 16))
+
+	var file bytes.Buffer
+	file.WriteString("RIFF")
+	binary.Write(&file, binary.LittleEndian, uint32(4+chunks.Len()))
+	file.WriteString("WEBP")
+	file.Write(chunks.Bytes())
+
+	anim, err := decodeWebPAnimation(file.Bytes())
+	if err == nil {
+		t.Fatalf("decoded a %dx%d canvas, want an error", 0x1000000, 0x1000000)
+	}
+	if anim != nil {
+		t.Errorf("got an animation alongside the error %v", err)
+	}
+	if _, err := LoadAnimation(writeTestFile(t, "huge.webp", file.Bytes())); err == nil {
+		t.Error("LoadAnimation accepted the oversized canvas")
+	}
+}
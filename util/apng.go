@@ -0,0 +1,210 @@
+package util
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"image"
+	"image/png"
+	"time"
+)
+
+var pngSignature = []byte("\x89PNG\r\n\x1a\n")
+
+// APNG dispose and blend operations as defined by the fcTL chunk.
+const (
+	apngDisposeNone       = 0
+	apngDisposeBackground = 1
+	apngDisposePrevious   = 2
+
+	apngBlendOver = 1
+)
+
+// pngChunk is a raw PNG chunk without its length and CRC.
+type pngChunk struct {
+	Type string
+	Data []byte
+}
+
+// apngFrame holds the frame control data and image data of a single APNG frame.
+type apngFrame struct {
+	rect    image.Rectangle
+	delay   time.Duration
+	dispose byte
+	blend   byte
+	data    []byte
+}
+
+// readPNGChunks splits a PNG file into its chunks, up to and including IEND.
+// Data after IEND is ignored, as the standard decoder does.
+func readPNGChunks(data []byte) ([]pngChunk, error) {
+	var chunks []pngChunk
+	data = data[len(pngSignature):]
+	for len(data) >= 12 {
+		length := binary.BigEndian.Uint32(data[0:4])
+		if uint64(length)+12 > uint64(len(data)) {
+			return nil, errors.New("truncated PNG chunk")
+		}
+		chunk := pngChunk{
+			Type: string(data[4:8]),
+			Data: data[8 : 8+length],
+		}
+		chunks = append(chunks, chunk)
+		if chunk.Type == "IEND" {
+			break
+		}
+		data = data[12+length:]
+	}
+	return chunks, nil
+}
+
+// writePNGChunk appends a chunk with its length and CRC to the buffer.
+func writePNGChunk(buf *bytes.Buffer, chunkType string, data []byte) {
+	var header [8]byte
+	binary.BigEndian.PutUint32(header[0:4], uint32(len(data)))
+	copy(header[4:8], chunkType)
+	buf.Write(header[:])
+	buf.Write(data)
+
+	crc := crc32.NewIEEE()
+	crc.Write(header[4:8])
+	crc.Write(data)
+	binary.BigEndian.PutUint32(header[0:4], crc.Sum32())
+	buf.Write(header[0:4])
+}
+
+// parseFCTL reads an fcTL chunk.
+func parseFCTL(data []byte) (apngFrame, error) {
+	if len(data) < 26 {
+		return apngFrame{}, errors.New("invalid fcTL chunk")
+	}
+	width := int(binary.BigEndian.Uint32(data[4:8]))
+	height := int(binary.BigEndian.Uint32(data[8:12]))
+	x := int(binary.BigEndian.Uint32(data[12:16]))
+	y := int(binary.BigEndian.Uint32(data[16:20]))
+	delayNum := binary.BigEndian.Uint16(data[20:22])
+	delayDen := binary.BigEndian.Uint16(data[22:24])
+	if delayDen == 0 {
+		delayDen = 100
+	}
+
+	delay := time.Duration(delayNum) * time.Second / time.Duration(delayDen)
+	if delay <= 0 {
+		delay = defaultFrameDelay
+	}
+
+	return apngFrame{
+		rect:    image.Rect(x, y, x+width, y+height),
+		delay:   delay,
+		dispose: data[24],
+		blend:   data[25],
+	}, nil
+}
+
+// decodeAPNG composites all frames of an animated PNG. It returns nil for regular PNG files.
+// Each frame is decoded by repackaging its data as a standalone PNG sharing the header and palette.
+func decodeAPNG(data []byte) (*Animation, error) {
+	chunks, err := readPNGChunks(data)
+	if err != nil {
+		return nil, err
+	}
+	if len(chunks) == 0 || chunks[0].Type != "IHDR" || len(chunks[0].Data) != 13 {
+		return nil, errors.New("missing IHDR chunk")
+	}
+	ihdr := chunks[0].Data
+
+	var animated bool
+	var shared []pngChunk
+	var frames []apngFrame
+	var current *apngFrame
+	seenIDAT := false
+
+	for _, chunk := range chunks[1:] {
+		switch chunk.Type {
+		case "acTL":
+			animated = true
+		case "fcTL":
+			frame, err := parseFCTL(chunk.Data)
+			if err != nil {
+				return nil, err
+			}
+			frames = append(frames, frame)
+			current = &frames[len(frames)-1]
+		case "IDAT":
+			seenIDAT = true
+			// The default image is only part of the animation if an fcTL chunk precedes it
+			if current != nil {
+				current.data = append(current.data, chunk.Data...)
+			}
+		case "fdAT":
+			if current != nil && len(chunk.Data) >= 4 {
+				current.data = append(current.data, chunk.Data[4:]...)
+			}
+		case "IEND":
+		default:
+			// Palette, transparency and color space chunks apply to every frame
+			if !seenIDAT {
+				shared = append(shared, chunk)
+			}
+		}
+	}
+	if !animated || len(frames) < 2 {
+		return nil, nil
+	}
+
+	canvasWidth := int(binary.BigEndian.Uint32(ihdr[0:4]))
+	canvasHeight := int(binary.BigEndian.Uint32(ihdr[4:8]))
+	if err := checkCanvasSize(canvasWidth, canvasHeight); err != nil {
+		return nil, err
+	}
+	canvas := image.NewRGBA(image.Rect(0, 0, canvasWidth, canvasHeight))
+
+	anim := &Animation{Frames: make([]Frame, 0, len(frames))}
+	for i, frame := range frames {
+		frameImage, err := decodeAPNGFrame(ihdr, shared, frame)
+		if err != nil {
+			return nil, err
+		}
+
+		var previous *image.RGBA
+		if frame.dispose == apngDisposePrevious {
+			previous = cloneRGBA(canvas)
+		}
+
+		compositeFrame(canvas, frameImage, frame.rect, frame.blend == apngBlendOver)
+		anim.Frames = append(anim.Frames, Frame{Image: cloneRGBA(canvas), Delay: frame.delay})
+
+		switch frame.dispose {
+		case apngDisposeBackground:
+			clearRect(canvas, frame.rect)
+		case apngDisposePrevious:
+			// Disposing the first frame to previous is treated as disposing to background
+			if i == 0 {
+				clearRect(canvas, frame.rect)
+			} else {
+				canvas = previous
+			}
+		}
+	}
+	return anim, nil
+}
+
+// decodeAPNGFrame builds a standalone PNG for a single frame and decodes it.
+func decodeAPNGFrame(ihdr []byte, shared []pngChunk, frame apngFrame) (image.Image, error) {
+	header := make([]byte, len(ihdr))
+	copy(header, ihdr)
+	binary.BigEndian.PutUint32(header[0:4], uint32(frame.rect.Dx()))
+	binary.BigEndian.PutUint32(header[4:8], uint32(frame.rect.Dy()))
+
+	var buf bytes.Buffer
+	buf.Write(pngSignature)
+	writePNGChunk(&buf, "IHDR", header)
+	for _, chunk := range shared {
+		writePNGChunk(&buf, chunk.Type, chunk.Data)
+	}
+	writePNGChunk(&buf, "IDAT", frame.data)
+	writePNGChunk(&buf, "IEND", nil)
+
+	return png.Decode(&buf)
+}
@@ -0,0 +1,77 @@
+package util
+
+import (
+	"image"
+
+	"github.com/disintegration/imaging"
+)
+
+// BlendMode is how a layer is combined with the image below it.
+type BlendMode int
+
+const (
+	// BlendNormal shows the layer itself, which at partial opacity is an onion skin.
+	BlendNormal BlendMode = iota
+	// BlendDifference shows the absolute difference of both images, black where they match.
+	BlendDifference
+	// BlendMultiply multiplies both images, which darkens everything but white.
+	BlendMultiply
+	// BlendScreen multiplies the inverted images and inverts the result, which lightens everything but black.
+	BlendScreen
+)
+
+func (m BlendMode) String() string {
+	switch m {
+	case BlendNormal:
+		return "normal"
+	case BlendDifference:
+		return "difference"
+	case BlendMultiply:
+		return "multiply"
+	case BlendScreen:
+		return "screen"
+	}
+	return "unknown"
+}
+
+// blendChannel combines one colour channel of the base and the layer, both 0-255.
+func (m BlendMode) blendChannel(base, layer int) int {
+	switch m {
+	case BlendDifference:
+		return absInt(base - layer)
+	case BlendMultiply:
+		return base * layer / 255
+	case BlendScreen:
+		return 255 - (255-base)*(255-layer)/255
+	}
+	return layer
+}
+
+// BlendImages draws layer over base with the given blend mode and opacity (0 to 1), aligned at their top left corners.
+// The result has the size of base, which shows through where the layer is transparent or does not reach.
+func BlendImages(base, layer image.Image, mode BlendMode, opacity float64) *image.NRGBA {
+	opacity = max(0, min(1, opacity))
+	dst := imaging.Clone(base)
+	top := imaging.Clone(layer)
+
+	width := min(dst.Bounds().Dx(), top.Bounds().Dx())
+	height := min(dst.Bounds().Dy(), top.Bounds().Dy())
+	for y := 0; y < height; y++ {
+		dstRow := dst.Pix[y*dst.Stride : y*dst.Stride+width*4]
+		topRow := top.Pix[y*top.Stride : y*top.Stride+width*4]
+		for i := 0; i < len(dstRow); i += 4 {
+			alpha := opacity * float64(topRow[i+3]) / 255
+			if alpha == 0 {
+				continue
+			}
+			for c := 0; c < 3; c++ {
+				base := int(dstRow[i+c])
+				blended := mode.blendChannel(base, int(topRow[i+c]))
+				dstRow[i+c] = uint8(float64(base) + float64(blended-base)*alpha + 0.5)
+			}
+			// The layer covers the base like a sheet of its opacity
+			dstRow[i+3] = uint8(alpha*255 + float64(dstRow[i+3])*(1-alpha) + 0.5)
+		}
+	}
+	return dst
+}
@@ -0,0 +1,91 @@
+package util
+
+import (
+	"encoding/json"
+	"os"
+)
+
+// CzkawkaImage is a single entry of Czkawka's similar images JSON results.
+type CzkawkaImage struct {
+	Path   string `json:"path"`
+	Size   int64  `json:"size"`
+	Width  int    `json:"width"`
+	Height int    `json:"height"`
+}
+
+// ImagePair is a pair of images queued for comparison.
+type ImagePair struct {
+	Image1 CzkawkaImage
+	Image2 CzkawkaImage
+}
+
+// LoadCzkawkaResults reads the groups of similar images from a Czkawka JSON results file.
+func LoadCzkawkaResults(path string) ([][]CzkawkaImage, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var groups [][]CzkawkaImage
+	if err := json.Unmarshal(data, &groups); err != nil {
+		return nil, err
+	}
+	return groups, nil
+}
+
+// FileExists reports whether a file exists at the given path.
+func FileExists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
+
+// BuildPairQueue expands every group into all of its pairs.
+// Pairs with different dimensions (if sameDimensionsOnly is set), ignored pairs
+// and pairs where either file no longer exists are skipped.
+func BuildPairQueue(groups [][]CzkawkaImage, sameDimensionsOnly bool, ignored *IgnoreDB) []ImagePair {
+	var queue []ImagePair
+	for _, group := range groups {
+		for i := 0; i < len(group); i++ {
+			for j := i + 1; j < len(group); j++ {
+				image1, image2 := group[i], group[j]
+				if image1.Path == image2.Path {
+					continue
+				}
+				if sameDimensionsOnly && (image1.Width != image2.Width || image1.Height != image2.Height) {
+					continue
+				}
+				if ignored.Contains(image1.Path, image2.Path) {
+					continue
+				}
+				if !FileExists(image1.Path) || !FileExists(image2.Path) {
+					continue
+				}
+				queue = append(queue, ImagePair{Image1: image1, Image2: image2})
+			}
+		}
+	}
+	return queue
+}
+
+// BuildGroupQueue drops group members whose files no longer exist
+// and groups that are left with fewer than two members.
+func BuildGroupQueue(groups [][]CzkawkaImage) [][]CzkawkaImage {
+	var queue [][]CzkawkaImage
+	for _, group := range groups {
+		members := ExistingMembers(group)
+		if len(members) >= 2 {
+			queue = append(queue, members)
+		}
+	}
+	return queue
+}
+
+// ExistingMembers returns the members of a group whose files still exist.
+func ExistingMembers(group []CzkawkaImage) []CzkawkaImage {
+	var members []CzkawkaImage
+	for _, member := range group {
+		if FileExists(member.Path) {
+			members = append(members, member)
+		}
+	}
+	return members
+}
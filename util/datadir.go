@@ -0,0 +1,28 @@
+package util
+
+import (
+	"os"
+	"path/filepath"
+)
+
+// dataHome returns $XDG_DATA_HOME, or ~/.local/share if it is not set.
+func dataHome() (string, error) {
+	if dir := os.Getenv("XDG_DATA_HOME"); dir != "" {
+		return dir, nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, ".local", "share"), nil
+}
+
+// DataDir returns the directory imgcomp keeps its persistent data in,
+// imgcomp under $XDG_DATA_HOME or ~/.local/share.
+func DataDir() (string, error) {
+	dir, err := dataHome()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "imgcomp"), nil
+}
@@ -0,0 +1,123 @@
+package util
+
+import (
+	"image"
+	"image/color"
+	"runtime"
+	"sync"
+)
+
+// Differences are multiplied by this factor in the diff image, so that small errors are visible.
+const diffAmplification = 5
+
+// rowReader writes one row of an image into dst as 16-bit premultiplied R, G, B and A values,
+// exactly as color.Color.RGBA would return them. y is relative to the top of the image.
+type rowReader func(y int, dst []uint32)
+
+// newRowReader returns a rowReader reading the pixel buffers of the common image types directly,
+// falling back to At for other types.
+func newRowReader(img image.Image) rowReader {
+	bounds := img.Bounds()
+	width := bounds.Dx()
+	switch src := img.(type) {
+	case *image.RGBA:
+		return func(y int, dst []uint32) {
+			row := src.Pix[src.PixOffset(bounds.Min.X, bounds.Min.Y+y):][:width*4]
+			for i, v := range row {
+				dst[i] = uint32(v) * 0x101
+			}
+		}
+	case *image.NRGBA:
+		return func(y int, dst []uint32) {
+			row := src.Pix[src.PixOffset(bounds.Min.X, bounds.Min.Y+y):][:width*4]
+			for i := 0; i < len(row); i += 4 {
+				dst[i], dst[i+1], dst[i+2], dst[i+3] = color.NRGBA{R: row[i], G: row[i+1], B: row[i+2], A: row[i+3]}.RGBA()
+			}
+		}
+	case *image.YCbCr:
+		return func(y int, dst []uint32) {
+			for x := 0; x < width; x++ {
+				yi := src.YOffset(bounds.Min.X+x, bounds.Min.Y+y)
+				ci := src.COffset(bounds.Min.X+x, bounds.Min.Y+y)
+				dst[x*4], dst[x*4+1], dst[x*4+2], dst[x*4+3] = color.YCbCr{Y: src.Y[yi], Cb: src.Cb[ci], Cr: src.Cr[ci]}.RGBA()
+			}
+		}
+	case *image.Gray:
+		return func(y int, dst []uint32) {
+			row := src.Pix[src.PixOffset(bounds.Min.X, bounds.Min.Y+y):][:width]
+			for x, v := range row {
+				gray := uint32(v) * 0x101
+				dst[x*4], dst[x*4+1], dst[x*4+2], dst[x*4+3] = gray, gray, gray, 0xffff
+			}
+		}
+	}
+	return func(y int, dst []uint32) {
+		for x := 0; x < width; x++ {
+			dst[x*4], dst[x*4+1], dst[x*4+2], dst[x*4+3] = img.At(bounds.Min.X+x, bounds.Min.Y+y).RGBA()
+		}
+	}
+}
+
+// ComputeImageDiffFast computes the pixel-wise difference between two images.
+// It returns a new image showing the differences and the error statistics of the comparison.
+// Pixels with any channel differing by more than threshold are counted as differing.
+// The rows are split into bands that are compared in parallel.
+func ComputeImageDiffFast(
+	img1, img2 *image.Image,
+	algo ScalingAlgorithm,
+	differenceAsMonochrome bool,
+	threshold uint32,
+) (image.Image, DiffStats) {
+	bounds := (*img1).Bounds()
+	// Computing difference requires both images to have the same bounds.
+	img22 := MatchBounds(*img1, *img2, algo)
+
+	diff := image.NewRGBA(bounds)
+	width, height := bounds.Dx(), bounds.Dy()
+	read1, read2 := newRowReader(*img1), newRowReader(img22)
+
+	bands := max(1, min(runtime.GOMAXPROCS(0), height))
+	accs := make([]diffAccumulator, bands)
+	var wg sync.WaitGroup
+	for band := range accs {
+		accs[band].threshold = threshold
+		wg.Add(1)
+		go func(acc *diffAccumulator, start, end int) {
+			defer wg.Done()
+			row1 := make([]uint32, width*4)
+			row2 := make([]uint32, width*4)
+			for y := start; y < end; y++ {
+				read1(y, row1)
+				read2(y, row2)
+				out := diff.Pix[y*diff.Stride : y*diff.Stride+width*4]
+				for i := 0; i < len(row1); i += 4 {
+					dr := absDiff(row1[i], row2[i]) >> 8
+					dg := absDiff(row1[i+1], row2[i+1]) >> 8
+					db := absDiff(row1[i+2], row2[i+2]) >> 8
+					da := absDiff(row1[i+3], row2[i+3]) >> 8
+
+					if differenceAsMonochrome {
+						// Every difference is shown in the same red, no difference in black
+						out[i], out[i+1], out[i+2] = 0, 0, 0
+						if dr != 0 || dg != 0 || db != 0 {
+							out[i] = 240
+						}
+					} else {
+						out[i] = uint8(min(dr*diffAmplification, 255))
+						out[i+1] = uint8(min(dg*diffAmplification, 255))
+						out[i+2] = uint8(min(db*diffAmplification, 255))
+					}
+					out[i+3] = 255
+
+					acc.add(dr, dg, db, da)
+				}
+			}
+		}(&accs[band], band*height/bands, (band+1)*height/bands)
+	}
+	wg.Wait()
+
+	for i := 1; i < len(accs); i++ {
+		accs[0].merge(&accs[i])
+	}
+	return diff, accs[0].stats()
+}
@@ -0,0 +1,148 @@
+package util
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/color/palette"
+	"math/rand"
+	"testing"
+)
+
+// genericImageDiff is the straightforward diff through At().RGBA() on a single goroutine,
+// which ComputeImageDiffFast must match exactly.
+func genericImageDiff(img1, img2 image.Image, monochrome bool, threshold uint32) (*image.RGBA, DiffStats) {
+	bounds := img1.Bounds()
+	diff := image.NewRGBA(bounds)
+	acc := diffAccumulator{threshold: threshold}
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			r1, g1, b1, a1 := img1.At(x, y).RGBA()
+			r2, g2, b2, a2 := img2.At(x, y).RGBA()
+			dr, dg, db, da := absDiff(r1, r2)>>8, absDiff(g1, g2)>>8, absDiff(b1, b2)>>8, absDiff(a1, a2)>>8
+
+			c := color.RGBA{R: uint8(min(dr*5, 255)), G: uint8(min(dg*5, 255)), B: uint8(min(db*5, 255)), A: 255}
+			if monochrome {
+				c = color.RGBA{A: 255}
+				if dr != 0 || dg != 0 || db != 0 {
+					c.R = 240
+				}
+			}
+			diff.Set(x, y, c)
+			acc.add(dr, dg, db, da)
+		}
+	}
+	return diff, acc.stats()
+}
+
+// atOnly hides the concrete type of an image, forcing the At fallback.
+type atOnly struct {
+	image.Image
+}
+
+// testBounds is deliberately not at the origin.
+var testBounds = image.Rect(3, 5, 3+37, 5+29)
+
+func randomNRGBA(r *rand.Rand, bounds image.Rectangle) *image.NRGBA {
+	img := image.NewNRGBA(bounds)
+	for i := range img.Pix {
+		img.Pix[i] = uint8(r.Intn(256))
+	}
+	return img
+}
+
+func randomYCbCr(r *rand.Rand, bounds image.Rectangle, ratio image.YCbCrSubsampleRatio) *image.YCbCr {
+	img := image.NewYCbCr(bounds, ratio)
+	for i := range img.Y {
+		img.Y[i] = uint8(r.Intn(256))
+	}
+	for i := range img.Cb {
+		img.Cb[i] = uint8(r.Intn(256))
+		img.Cr[i] = uint8(r.Intn(256))
+	}
+	return img
+}
+
+// testImages returns an image of every type with a fast path, plus ones that use the fallback, all with testBounds.
+func testImages() map[string]image.Image {
+	r := rand.New(rand.NewSource(1))
+
+	rgba := image.NewRGBA(testBounds)
+	for i := 0; i < len(rgba.Pix); i += 4 {
+		a := uint8(r.Intn(256))
+		// Premultiplied colour values cannot exceed alpha
+		rgba.Pix[i], rgba.Pix[i+1], rgba.Pix[i+2], rgba.Pix[i+3] = uint8(r.Intn(int(a)+1)), uint8(r.Intn(int(a)+1)), uint8(r.Intn(int(a)+1)), a
+	}
+	gray := image.NewGray(testBounds)
+	for i := range gray.Pix {
+		gray.Pix[i] = uint8(r.Intn(256))
+	}
+	paletted := image.NewPaletted(testBounds, palette.Plan9)
+	for i := range paletted.Pix {
+		paletted.Pix[i] = uint8(r.Intn(256))
+	}
+	// A sub-image whose pixel buffer starts before its bounds
+	large := randomNRGBA(r, image.Rect(0, 0, 60, 50))
+
+	return map[string]image.Image{
+		"RGBA":        rgba,
+		"NRGBA":       randomNRGBA(r, testBounds),
+		"NRGBA sub":   large.SubImage(testBounds),
+		"YCbCr 4:2:0": randomYCbCr(r, testBounds, image.YCbCrSubsampleRatio420),
+		"YCbCr 4:4:4": randomYCbCr(r, testBounds, image.YCbCrSubsampleRatio444),
+		"Gray":        gray,
+		"Paletted":    paletted,
+		"At only":     atOnly{randomNRGBA(r, testBounds)},
+	}
+}
+
+func TestComputeImageDiffFastMatchesGenericDiff(t *testing.T) {
+	images := testImages()
+	for name1, img1 := range images {
+		for name2, img2 := range images {
+			for _, monochrome := range []bool{false, true} {
+				wantDiff, wantStats := genericImageDiff(img1, img2, monochrome, DefaultDiffThreshold)
+				gotDiff, gotStats := ComputeImageDiffFast(&img1, &img2, Bilinear, monochrome, DefaultDiffThreshold)
+
+				if gotStats != wantStats {
+					t.Errorf("%s vs %s (monochrome %v): stats %+v, want %+v", name1, name2, monochrome, gotStats, wantStats)
+				}
+				got := gotDiff.(*image.RGBA)
+				if !got.Bounds().Eq(wantDiff.Bounds()) || !bytes.Equal(got.Pix, wantDiff.Pix) {
+					t.Errorf("%s vs %s (monochrome %v): diff images differ", name1, name2, monochrome)
+				}
+			}
+		}
+	}
+}
+
+func TestComputeImageDiffFastIdentical(t *testing.T) {
+	img := testImages()["YCbCr 4:2:0"]
+	_, stats := ComputeImageDiffFast(&img, &img, Bilinear, false, DefaultDiffThreshold)
+	if !stats.Identical() || stats.PixelCount != uint64(testBounds.Dx()*testBounds.Dy()) {
+		t.Errorf("stats of an image against itself: %+v", stats)
+	}
+}
+
+// benchmarkImages returns a 12 megapixel photo-like YCbCr image and an NRGBA image to compare it with.
+func benchmarkImages() (image.Image, image.Image) {
+	r := rand.New(rand.NewSource(1))
+	bounds := image.Rect(0, 0, 4000, 3000)
+	return randomYCbCr(r, bounds, image.YCbCrSubsampleRatio420), randomNRGBA(r, bounds)
+}
+
+func BenchmarkGenericImageDiff(b *testing.B) {
+	img1, img2 := benchmarkImages()
+	b.ReportAllocs()
+	for b.Loop() {
+		genericImageDiff(img1, img2, false, DefaultDiffThreshold)
+	}
+}
+
+func BenchmarkComputeImageDiffFast(b *testing.B) {
+	img1, img2 := benchmarkImages()
+	b.ReportAllocs()
+	for b.Loop() {
+		ComputeImageDiffFast(&img1, &img2, Bilinear, false, DefaultDiffThreshold)
+	}
+}
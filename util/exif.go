@@ -0,0 +1,217 @@
+package util
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+	"io"
+	"os"
+)
+
+// ApplyOrientation controls whether LoadImage rotates and flips images according to their EXIF orientation tag.
+var ApplyOrientation = true
+
+// OrientationNormal is the EXIF orientation of images that need no transformation.
+const OrientationNormal = 1
+
+const exifOrientationTag = 0x0112
+
+// maxExifSize is the largest EXIF block that is read. JPEG limits EXIF data to 64 KiB,
+// so larger lengths in PNG or WebP chunks are taken as corrupt instead of being allocated.
+const maxExifSize = 1 << 20
+
+// orientationNames describes the EXIF orientation values 1-8.
+var orientationNames = map[int]string{
+	1: "normal",
+	2: "mirror horizontally",
+	3: "rotate 180°",
+	4: "mirror vertically",
+	5: "mirror horizontally and rotate 270° clockwise",
+	6: "rotate 90° clockwise",
+	7: "mirror horizontally and rotate 90° clockwise",
+	8: "rotate 270° clockwise",
+}
+
+// OrientationName returns a human readable description of an EXIF orientation value.
+func OrientationName(orientation int) string {
+	if name, ok := orientationNames[orientation]; ok {
+		return name
+	}
+	return "unknown"
+}
+
+// ReadOrientation returns the EXIF orientation tag of a JPEG, PNG or WebP file.
+// Files without EXIF data, or in other formats, report OrientationNormal.
+func ReadOrientation(path string) int {
+	file, err := os.Open(path)
+	if err != nil {
+		return OrientationNormal
+	}
+	defer file.Close()
+
+	var magic [12]byte
+	if _, err := io.ReadFull(file, magic[:]); err != nil {
+		return OrientationNormal
+	}
+
+	var tiff []byte
+	switch {
+	case magic[0] == 0xFF && magic[1] == 0xD8:
+		tiff = findJPEGExif(file)
+	case bytes.Equal(magic[:8], pngSignature):
+		tiff = findPNGExif(file)
+	case string(magic[0:4]) == "RIFF" && string(magic[8:12]) == "WEBP":
+		tiff = findWebPExif(file)
+	}
+
+	// Some writers keep the JPEG "Exif\0\0" prefix in PNG and WebP chunks as well
+	tiff = bytes.TrimPrefix(tiff, []byte("Exif\x00\x00"))
+	if orientation := parseTIFFOrientation(tiff); orientation >= 1 && orientation <= 8 {
+		return orientation
+	}
+	return OrientationNormal
+}
+
+// findJPEGExif walks the JPEG segments until the Exif APP1 segment or the start of scan.
+func findJPEGExif(file *os.File) []byte {
+	if _, err := file.Seek(2, io.SeekStart); err != nil {
+		return nil
+	}
+	var header [4]byte
+	for {
+		if _, err := io.ReadFull(file, header[:]); err != nil || header[0] != 0xFF {
+			return nil
+		}
+		marker := header[1]
+		length := int64(binary.BigEndian.Uint16(header[2:4])) - 2
+		if marker == 0xDA || length < 0 {
+			return nil
+		}
+		if marker == 0xE1 {
+			segment := readExifChunk(file, length)
+			if segment == nil {
+				return nil
+			}
+			if bytes.HasPrefix(segment, []byte("Exif\x00\x00")) {
+				return segment
+			}
+			continue
+		}
+		if _, err := file.Seek(length, io.SeekCurrent); err != nil {
+			return nil
+		}
+	}
+}
+
+// findPNGExif walks the PNG chunks until the eXIf chunk or the end of the file.
+func findPNGExif(file *os.File) []byte {
+	if _, err := file.Seek(int64(len(pngSignature)), io.SeekStart); err != nil {
+		return nil
+	}
+	var header [8]byte
+	for {
+		if _, err := io.ReadFull(file, header[:]); err != nil {
+			return nil
+		}
+		length := int64(binary.BigEndian.Uint32(header[0:4]))
+		switch string(header[4:8]) {
+		case "eXIf":
+			return readExifChunk(file, length)
+		case "IEND":
+			return nil
+		}
+		// Skip the chunk data and its CRC
+		if _, err := file.Seek(length+4, io.SeekCurrent); err != nil {
+			return nil
+		}
+	}
+}
+
+// findWebPExif walks the RIFF chunks of a WebP file until the EXIF chunk.
+func findWebPExif(file *os.File) []byte {
+	var header [8]byte
+	for {
+		if _, err := io.ReadFull(file, header[:]); err != nil {
+			return nil
+		}
+		size := int64(binary.LittleEndian.Uint32(header[4:8]))
+		if string(header[0:4]) == "EXIF" {
+			return readExifChunk(file, size)
+		}
+		if _, err := file.Seek(size+size&1, io.SeekCurrent); err != nil {
+			return nil
+		}
+	}
+}
+
+// readExifChunk reads length bytes at the current position of the file. The length comes from the file itself,
+// so lengths beyond maxExifSize or the end of the file are rejected before anything is allocated.
+func readExifChunk(file *os.File, length int64) []byte {
+	if length > maxExifSize {
+		return nil
+	}
+	info, err := file.Stat()
+	if err != nil {
+		return nil
+	}
+	offset, err := file.Seek(0, io.SeekCurrent)
+	if err != nil || length > info.Size()-offset {
+		return nil
+	}
+	chunk := make([]byte, length)
+	if _, err := io.ReadFull(file, chunk); err != nil {
+		return nil
+	}
+	return chunk
+}
+
+// parseTIFFOrientation reads the orientation tag from the first IFD of TIFF-structured EXIF data.
+func parseTIFFOrientation(tiff []byte) int {
+	if len(tiff) < 8 {
+		return 0
+	}
+	var order binary.ByteOrder
+	switch string(tiff[0:2]) {
+	case "II":
+		order = binary.LittleEndian
+	case "MM":
+		order = binary.BigEndian
+	default:
+		return 0
+	}
+
+	offset := int(order.Uint32(tiff[4:8]))
+	if offset < 8 || offset+2 > len(tiff) {
+		return 0
+	}
+	entries := int(order.Uint16(tiff[offset : offset+2]))
+	for i := 0; i < entries; i++ {
+		entry := offset + 2 + i*12
+		if entry+12 > len(tiff) {
+			return 0
+		}
+		if order.Uint16(tiff[entry:entry+2]) == exifOrientationTag {
+			return int(order.Uint16(tiff[entry+8 : entry+10]))
+		}
+	}
+	return 0
+}
+
+// orientationTransforms maps EXIF orientation values to the transform that displays the image upright.
+var orientationTransforms = map[int]Transform{
+	2: FlipHorizontal,
+	3: Rotate180,
+	4: FlipVertical,
+	5: Transpose,
+	6: Rotate90,
+	7: Transverse,
+	8: Rotate270,
+}
+
+// orientImage transforms an image so that it is displayed upright according to its EXIF orientation.
+func orientImage(img image.Image, orientation int) image.Image {
+	if t, ok := orientationTransforms[orientation]; ok {
+		return t.Apply(img)
+	}
+	return img
+}
@@ -0,0 +1,67 @@
+package util
+
+import (
+	"encoding/binary"
+	"runtime"
+	"testing"
+)
+
+// orientationTIFF is big-endian TIFF data whose first IFD holds only the orientation tag.
+func orientationTIFF(orientation uint16) []byte {
+	tiff := []byte("MM\x00\x2a\x00\x00\x00\x08\x00\x01")
+	tiff = binary.BigEndian.AppendUint16(tiff, exifOrientationTag)
+	tiff = append(tiff, 0, 3, 0, 0, 0, 1)
+	tiff = binary.BigEndian.AppendUint16(tiff, orientation)
+	return append(tiff, 0, 0, 0, 0, 0, 0)
+}
+
+// pngWithExif returns a PNG signature followed by an eXIf chunk declaring the given length and an IEND chunk.
+func pngWithExif(length uint32, data []byte) []byte {
+	file := append([]byte{}, pngSignature...)
+	file = binary.BigEndian.AppendUint32(file, length)
+	file = append(file, "eXIf"...)
+	file = append(file, data...)
+	file = append(file, 0, 0, 0, 0)
+	return append(file, 0, 0, 0, 0, 'I', 'E', 'N', 'D', 0, 0, 0, 0)
+}
+
+// webPWithExif returns a RIFF WebP header followed by an EXIF chunk declaring the given size.
+func webPWithExif(size uint32, data []byte) []byte {
+	file := []byte("RIFF\x00\x00\x00\x00WEBPEXIF")
+	file = binary.LittleEndian.AppendUint32(file, size)
+	return append(file, data...)
+}
+
+func TestReadOrientation(t *testing.T) {
+	tiff := orientationTIFF(6)
+	for name, data := range map[string][]byte{
+		"exif.png":  pngWithExif(uint32(len(tiff)), tiff),
+		"exif.webp": webPWithExif(uint32(len(tiff)), tiff),
+	} {
+		if orientation := ReadOrientation(writeTestFile(t, name, data)); orientation != 6 {
+			t.Errorf("%s: orientation %d, want 6", name, orientation)
+		}
+	}
+}
+
+func TestReadOrientationRejectsHugeChunkLengths(t *testing.T) {
+	tiff := orientationTIFF(6)
+	for name, data := range map[string][]byte{
+		"huge.png":     pngWithExif(0xfffffff0, tiff),
+		"huge.webp":    webPWithExif(0xfffffff0, tiff),
+		"past-eof.png": pngWithExif(maxExifSize/2, tiff),
+	} {
+		path := writeTestFile(t, name, data)
+		var before, after runtime.MemStats
+		runtime.ReadMemStats(&before)
+		orientation := ReadOrientation(path)
+		runtime.ReadMemStats(&after)
+
+		if orientation != OrientationNormal {
+			t.Errorf("%s: orientation %d, want %d", name, orientation, OrientationNormal)
+		}
+		if allocated := after.TotalAlloc - before.TotalAlloc; allocated > 64<<10 {
+			t.Errorf("%s: allocated %d bytes for a corrupt chunk", name, allocated)
+		}
+	}
+}
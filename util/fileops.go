@@ -0,0 +1,269 @@
+package util
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// Name of the hidden directories next to deleted files that hold them.
+const holdingDirName = ".imgcomp-deleted"
+
+// HoldingArea keeps deleted files until the end of the session, so that deletions can be undone.
+// Files are held in a hidden directory next to them, so that holding one is a rename on the same volume.
+// Every directory used is recorded in a file of the session inside the registry directory,
+// so that SweepHoldingAreas can restore what a session that did not end properly left behind.
+type HoldingArea struct {
+	registry string
+	session  string
+	dirs     map[string]bool // Directories of this session created so far
+}
+
+// NewHoldingArea creates a holding area for this session, recorded in the given registry directory.
+func NewHoldingArea(registry string) *HoldingArea {
+	return &HoldingArea{
+		registry: registry,
+		session:  fmt.Sprintf("%s-%d", time.Now().Format("20060102-150405.000000000"), os.Getpid()),
+		dirs:     map[string]bool{},
+	}
+}
+
+// Hold moves the file into the holding area and returns its new location.
+func (h *HoldingArea) Hold(path string) (string, error) {
+	path, err := filepath.Abs(path)
+	if err != nil {
+		return "", err
+	}
+	dir := filepath.Join(filepath.Dir(path), holdingDirName, h.session)
+	if !h.dirs[dir] {
+		// Recorded before it exists, so that a crash never leaves an unrecorded directory behind
+		if err := h.record(dir); err != nil {
+			return "", err
+		}
+		h.dirs[dir] = true
+	}
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return "", err
+	}
+	held, err := os.MkdirTemp(dir, "")
+	if err != nil {
+		return "", err
+	}
+	destination := filepath.Join(held, filepath.Base(path))
+	if err := moveFile(path, destination); err != nil {
+		os.Remove(held)
+		return "", err
+	}
+	return destination, nil
+}
+
+// record appends a directory to the record file of the session.
+func (h *HoldingArea) record(dir string) error {
+	if err := os.MkdirAll(h.registry, 0700); err != nil {
+		return err
+	}
+	file, err := os.OpenFile(filepath.Join(h.registry, h.session), os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0600)
+	if err != nil {
+		return err
+	}
+	if _, err := fmt.Fprintln(file, dir); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// Restore moves a held file back to its original path, refusing to overwrite a file created there in the meantime.
+func (h *HoldingArea) Restore(held, original string) error {
+	if FileExists(original) {
+		return fmt.Errorf("cannot restore %s: a file with that name exists", original)
+	}
+	if err := moveFile(held, original); err != nil {
+		return err
+	}
+	return os.Remove(filepath.Dir(held))
+}
+
+// Purge permanently removes all held files.
+func (h *HoldingArea) Purge() error {
+	var errs []error
+	for dir := range h.dirs {
+		if err := removeHoldingDir(dir); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if len(errs) > 0 {
+		return errors.Join(errs...)
+	}
+	h.dirs = map[string]bool{}
+	if err := os.Remove(filepath.Join(h.registry, h.session)); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+// removeHoldingDir removes the directory of a session, and the hidden directory around it once no session uses it.
+func removeHoldingDir(dir string) error {
+	if err := os.RemoveAll(dir); err != nil {
+		return err
+	}
+	os.Remove(filepath.Dir(dir))
+	return nil
+}
+
+// SweepHoldingAreas moves the files held by sessions recorded in the registry whose process is no longer running
+// back to where they were deleted from, and returns their paths. This happens when the program crashed or was killed
+// before its deletions became final, so they are undone rather than made permanent.
+// Files whose path has been taken in the meantime stay held, and are reported in the error.
+func SweepHoldingAreas(registry string) ([]string, error) {
+	entries, err := os.ReadDir(registry)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var restored []string
+	var errs []error
+	for _, entry := range entries {
+		// Earlier versions held the files themselves in the registry, without their original location,
+		// so those directories are left for the user to recover from
+		if entry.IsDir() {
+			continue
+		}
+		files, err := sweepSession(filepath.Join(registry, entry.Name()))
+		restored = append(restored, files...)
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return restored, errors.Join(errs...)
+}
+
+// sweepSession restores the files in the directories listed in the record file of a session,
+// then removes the record, unless the session is still running.
+// The record is kept while any of its files could not be restored, so that the next sweep tries again.
+func sweepSession(record string) ([]string, error) {
+	session := filepath.Base(record)
+	separator := strings.LastIndexByte(session, '-')
+	pid, err := strconv.Atoi(session[separator+1:])
+	if separator < 0 || err != nil {
+		return nil, fmt.Errorf("%s is not a holding area record", record)
+	}
+	if processRunning(pid) {
+		return nil, nil
+	}
+
+	data, err := os.ReadFile(record)
+	if err != nil {
+		return nil, err
+	}
+	var restored []string
+	var errs []error
+	for _, dir := range strings.Split(strings.TrimSpace(string(data)), "\n") {
+		// Only ever touches directories shaped like those Hold creates, whatever the record says
+		if dir == "" || filepath.Base(dir) != session || filepath.Base(filepath.Dir(dir)) != holdingDirName {
+			continue
+		}
+		files, err := restoreHoldingDir(dir)
+		restored = append(restored, files...)
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if len(errs) > 0 {
+		return restored, errors.Join(errs...)
+	}
+	return restored, os.Remove(record)
+}
+
+// restoreHoldingDir moves every file held in the directory of a session back next to its hidden directory,
+// and removes the directories it leaves empty.
+func restoreHoldingDir(dir string) ([]string, error) {
+	holds, err := os.ReadDir(dir)
+	if errors.Is(err, os.ErrNotExist) {
+		// Recorded but never created, or already purged
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	parent := filepath.Dir(filepath.Dir(dir))
+	var restored []string
+	var errs []error
+	for _, hold := range holds {
+		heldDir := filepath.Join(dir, hold.Name())
+		files, err := os.ReadDir(heldDir)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		for _, file := range files {
+			held, original := filepath.Join(heldDir, file.Name()), filepath.Join(parent, file.Name())
+			if _, err := os.Lstat(original); err == nil {
+				errs = append(errs, fmt.Errorf("cannot restore %s: a file with that name exists, the deleted file is kept at %s", original, held))
+				continue
+			}
+			if err := moveFile(held, original); err != nil {
+				errs = append(errs, err)
+				continue
+			}
+			restored = append(restored, original)
+		}
+		// Only succeed once the directories are empty
+		os.Remove(heldDir)
+	}
+	os.Remove(dir)
+	os.Remove(filepath.Dir(dir))
+	return restored, errors.Join(errs...)
+}
+
+// moveFile renames a file, falling back to copying it when the destination is on another file system.
+func moveFile(src, dst string) error {
+	err := os.Rename(src, dst)
+	if !errors.Is(err, syscall.EXDEV) {
+		return err
+	}
+
+	if err := copyFile(src, dst); err != nil {
+		return err
+	}
+	return os.Remove(src)
+}
+
+// copyFile copies a file along with its permissions and modification time.
+// It never overwrites an existing file and removes the partial copy on failure.
+func copyFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	info, err := in.Stat()
+	if err != nil {
+		return err
+	}
+
+	out, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_EXCL, info.Mode().Perm())
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		os.Remove(dst)
+		return err
+	}
+	if err := out.Close(); err != nil {
+		os.Remove(dst)
+		return err
+	}
+	return os.Chtimes(dst, info.ModTime(), info.ModTime())
+}
@@ -0,0 +1,15 @@
+//go:build !unix
+
+package util
+
+import "os"
+
+// processRunning reports whether a process with the given ID exists, which os.FindProcess checks outside of Unix.
+func processRunning(pid int) bool {
+	process, err := os.FindProcess(pid)
+	if err != nil {
+		return false
+	}
+	process.Release()
+	return true
+}
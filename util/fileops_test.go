@@ -0,0 +1,97 @@
+package util
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestHoldingAreaHoldsFilesNextToThem(t *testing.T) {
+	dir, registry := t.TempDir(), t.TempDir()
+	path := filepath.Join(dir, "a.png")
+	if err := os.WriteFile(path, []byte("image"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	area := NewHoldingArea(registry)
+	held, err := area.Hold(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rel, err := filepath.Rel(filepath.Join(dir, holdingDirName), held); err != nil || !filepath.IsLocal(rel) {
+		t.Errorf("%s is not held next to %s", held, path)
+	}
+	if FileExists(path) {
+		t.Error("held file still at its path")
+	}
+
+	if err := area.Purge(); err != nil {
+		t.Fatal(err)
+	}
+	if FileExists(filepath.Join(dir, holdingDirName)) {
+		t.Error("holding directory not removed")
+	}
+	if entries, _ := os.ReadDir(registry); len(entries) != 0 {
+		t.Errorf("records left after purging: %v", entries)
+	}
+}
+
+func TestSweepHoldingAreas(t *testing.T) {
+	dir, registry := t.TempDir(), t.TempDir()
+
+	// A session of this process is still running, one of a process that does not exist has crashed
+	running := NewHoldingArea(registry)
+	crashed := NewHoldingArea(registry)
+	crashed.session = fmt.Sprintf("20240101-000000.000000000-%d", 1<<30)
+
+	var held []string
+	for i, area := range []*HoldingArea{running, crashed, crashed} {
+		path := filepath.Join(dir, fmt.Sprintf("%d.png", i))
+		if err := os.WriteFile(path, []byte("image"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		h, err := area.Hold(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		held = append(held, h)
+	}
+	// The path of one file of the crashed session has been taken since
+	taken := filepath.Join(dir, "2.png")
+	if err := os.WriteFile(taken, []byte("new"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	restored, err := SweepHoldingAreas(registry)
+	if err == nil {
+		t.Error("no error for the file that cannot be restored")
+	}
+	if want := filepath.Join(dir, "1.png"); len(restored) != 1 || restored[0] != want {
+		t.Errorf("restored %v, want %s", restored, want)
+	}
+	if !FileExists(held[0]) || FileExists(filepath.Join(dir, "0.png")) {
+		t.Error("file of a running session restored")
+	}
+	if data, err := os.ReadFile(filepath.Join(dir, "1.png")); err != nil || string(data) != "image" {
+		t.Errorf("restored file reads %q, %v", data, err)
+	}
+	if data, _ := os.ReadFile(taken); string(data) != "new" || !FileExists(held[2]) {
+		t.Error("file overwritten or held file lost")
+	}
+	if !FileExists(filepath.Join(registry, crashed.session)) {
+		t.Error("record removed while a file is still held")
+	}
+
+	// Once the path is free again, the next sweep restores the file and forgets the session
+	if err := os.Remove(taken); err != nil {
+		t.Fatal(err)
+	}
+	restored, err = SweepHoldingAreas(registry)
+	if err != nil || len(restored) != 1 || restored[0] != taken {
+		t.Errorf("restored %v, %v", restored, err)
+	}
+	if FileExists(filepath.Join(registry, crashed.session)) || FileExists(filepath.Dir(filepath.Dir(held[1]))) {
+		t.Error("crashed session not swept")
+	}
+}
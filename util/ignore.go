@@ -0,0 +1,358 @@
+package util
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ignoreDBVersion is the version of the ignore database file format.
+const ignoreDBVersion = 1
+
+// IgnoreEntry is a pair of images the user has marked as not being duplicates.
+// The pair is identified by the content hashes of both files, ordered so that Hash1 < Hash2,
+// which keeps ignores working when files are renamed or the pair is seen in the other order.
+type IgnoreEntry struct {
+	Hash1 string    `json:"hash1"`
+	Hash2 string    `json:"hash2"`
+	Size1 int64     `json:"size1"`
+	Size2 int64     `json:"size2"`
+	Path1 string    `json:"path1"` // Last known path of the file with Hash1
+	Path2 string    `json:"path2"` // Last known path of the file with Hash2
+	Added time.Time `json:"added"`
+}
+
+type ignoreDBFile struct {
+	Version int           `json:"version"`
+	Pairs   []IgnoreEntry `json:"pairs"`
+}
+
+// fileHash is a cached content hash, valid as long as the size and modification time are unchanged.
+type fileHash struct {
+	size    int64
+	modTime time.Time
+	hash    string
+}
+
+// IgnoreDB is a persistent store of ignored image pairs.
+type IgnoreDB struct {
+	path    string
+	entries map[string]*IgnoreEntry
+	sizes   map[int64]int // Number of entries referencing each file size, used to skip hashing
+	hashes  map[string]fileHash
+	dirty   bool
+}
+
+// DefaultIgnoreDBPath returns the location of the ignore database in the data directory.
+func DefaultIgnoreDBPath() (string, error) {
+	dataDir, err := DataDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dataDir, "ignored_pairs.json"), nil
+}
+
+// OpenIgnoreDB reads the ignore database at the given path.
+// A missing file results in an empty database that is created on the first Save.
+func OpenIgnoreDB(path string) (*IgnoreDB, error) {
+	db := &IgnoreDB{
+		path:    path,
+		entries: make(map[string]*IgnoreEntry),
+		sizes:   make(map[int64]int),
+		hashes:  make(map[string]fileHash),
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return db, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var file ignoreDBFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, err
+	}
+	if file.Version > ignoreDBVersion {
+		return nil, errors.New("ignore database " + path + " was written by a newer version")
+	}
+	for _, entry := range file.Pairs {
+		db.insert(entry)
+	}
+	return db, nil
+}
+
+// ignoreKey builds the order-independent lookup key of a pair of content hashes.
+func ignoreKey(hash1, hash2 string) string {
+	if hash2 < hash1 {
+		hash1, hash2 = hash2, hash1
+	}
+	return hash1 + ":" + hash2
+}
+
+func (db *IgnoreDB) insert(entry IgnoreEntry) {
+	key := ignoreKey(entry.Hash1, entry.Hash2)
+	if _, ok := db.entries[key]; ok {
+		return
+	}
+	db.entries[key] = &entry
+	db.sizes[entry.Size1]++
+	db.sizes[entry.Size2]++
+}
+
+func (db *IgnoreDB) remove(key string) {
+	entry := db.entries[key]
+	delete(db.entries, key)
+	for _, size := range []int64{entry.Size1, entry.Size2} {
+		if db.sizes[size]--; db.sizes[size] <= 0 {
+			delete(db.sizes, size)
+		}
+	}
+}
+
+// hashFile returns the SHA-256 content hash and size of a file.
+// Hashes are cached until the size or modification time of the file changes.
+func (db *IgnoreDB) hashFile(path string) (string, int64, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return "", 0, err
+	}
+	if cached, ok := db.hashes[path]; ok && cached.size == info.Size() && cached.modTime.Equal(info.ModTime()) {
+		return cached.hash, cached.size, nil
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return "", 0, err
+	}
+	defer file.Close()
+	hasher := sha256.New()
+	if _, err := io.Copy(hasher, file); err != nil {
+		return "", 0, err
+	}
+	hash := hex.EncodeToString(hasher.Sum(nil))
+	db.hashes[path] = fileHash{size: info.Size(), modTime: info.ModTime(), hash: hash}
+	return hash, info.Size(), nil
+}
+
+// Add marks the pair as ignored and saves the database.
+func (db *IgnoreDB) Add(path1, path2 string) error {
+	if err := db.add(path1, path2); err != nil {
+		return err
+	}
+	return db.Save()
+}
+
+// add marks the pair as ignored without saving.
+func (db *IgnoreDB) add(path1, path2 string) error {
+	hash1, size1, err := db.hashFile(path1)
+	if err != nil {
+		return err
+	}
+	hash2, size2, err := db.hashFile(path2)
+	if err != nil {
+		return err
+	}
+	if hash2 < hash1 {
+		hash1, hash2, size1, size2, path1, path2 = hash2, hash1, size2, size1, path2, path1
+	}
+	db.insert(IgnoreEntry{
+		Hash1: hash1, Hash2: hash2,
+		Size1: size1, Size2: size2,
+		Path1: path1, Path2: path2,
+		Added: time.Now(),
+	})
+	db.dirty = true
+	return nil
+}
+
+// Remove forgets an ignored pair and saves the database.
+func (db *IgnoreDB) Remove(path1, path2 string) error {
+	hash1, _, err := db.hashFile(path1)
+	if err != nil {
+		return err
+	}
+	hash2, _, err := db.hashFile(path2)
+	if err != nil {
+		return err
+	}
+	key := ignoreKey(hash1, hash2)
+	if _, ok := db.entries[key]; !ok {
+		return nil
+	}
+	db.remove(key)
+	db.dirty = true
+	return db.Save()
+}
+
+// Contains reports whether the pair has been ignored, in either order and under any previous names.
+// Files are only hashed if their sizes match an ignored pair.
+// When a match is found under new paths, the entry's last known paths are updated.
+func (db *IgnoreDB) Contains(path1, path2 string) bool {
+	if len(db.entries) == 0 {
+		return false
+	}
+	info1, err1 := os.Stat(path1)
+	info2, err2 := os.Stat(path2)
+	if err1 != nil || err2 != nil || db.sizes[info1.Size()] == 0 || db.sizes[info2.Size()] == 0 {
+		return false
+	}
+
+	hash1, _, err := db.hashFile(path1)
+	if err != nil {
+		return false
+	}
+	hash2, _, err := db.hashFile(path2)
+	if err != nil {
+		return false
+	}
+	key := ignoreKey(hash1, hash2)
+	entry, ok := db.entries[key]
+	if !ok {
+		return false
+	}
+	if hash2 < hash1 {
+		path1, path2 = path2, path1
+	}
+	if entry.Path1 != path1 || entry.Path2 != path2 {
+		entry.Path1, entry.Path2 = path1, path2
+		db.dirty = true
+	}
+	return true
+}
+
+// Prune removes entries with a file whose content is not among the given files, such as every image of a scan.
+// Renamed and moved files are recognized by their content, so only pairs whose files are gone are dropped;
+// pairs from directories the given files do not cover are dropped as well.
+// Only files whose sizes match an entry are hashed. It returns the number of removed entries.
+func (db *IgnoreDB) Prune(paths []string) int {
+	present := make(map[string]bool)
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		if err != nil || db.sizes[info.Size()] == 0 {
+			continue
+		}
+		if hash, _, err := db.hashFile(path); err == nil {
+			present[hash] = true
+		}
+	}
+
+	removed := 0
+	for key, entry := range db.entries {
+		if !present[entry.Hash1] || !present[entry.Hash2] {
+			db.remove(key)
+			removed++
+		}
+	}
+	if removed > 0 {
+		db.dirty = true
+	}
+	return removed
+}
+
+// Len returns the number of ignored pairs.
+func (db *IgnoreDB) Len() int {
+	return len(db.entries)
+}
+
+// Save writes the database if it has changed since it was read.
+// The file is replaced atomically so that an interrupted write cannot lose existing ignores.
+func (db *IgnoreDB) Save() error {
+	if !db.dirty {
+		return nil
+	}
+	file := ignoreDBFile{Version: ignoreDBVersion, Pairs: make([]IgnoreEntry, 0, len(db.entries))}
+	for _, entry := range db.entries {
+		file.Pairs = append(file.Pairs, *entry)
+	}
+	// Oldest first, so that the file only grows at the end as pairs are added
+	sort.Slice(file.Pairs, func(i, j int) bool {
+		if !file.Pairs[i].Added.Equal(file.Pairs[j].Added) {
+			return file.Pairs[i].Added.Before(file.Pairs[j].Added)
+		}
+		return ignoreKey(file.Pairs[i].Hash1, file.Pairs[i].Hash2) < ignoreKey(file.Pairs[j].Hash1, file.Pairs[j].Hash2)
+	})
+	data, err := json.MarshalIndent(file, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(db.path), 0755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(db.path), ".ignored_pairs-*.json")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := os.Rename(tmp.Name(), db.path); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	db.dirty = false
+	return nil
+}
+
+// ImportTextList adds the pairs of a legacy ignore list with "path1:path2" lines.
+// Since paths may contain colons, every colon is tried as the separator until both sides name existing files.
+// Lines whose files no longer exist cannot be hashed and are skipped.
+// It returns the number of imported and skipped lines.
+func (db *IgnoreDB) ImportTextList(path string) (int, int, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return 0, 0, err
+	}
+	defer file.Close()
+
+	imported, skipped := 0, 0
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		path1, path2, ok := splitIgnoreLine(line)
+		if !ok {
+			skipped++
+			continue
+		}
+		if err := db.add(path1, path2); err != nil {
+			skipped++
+			continue
+		}
+		imported++
+	}
+	if err := scanner.Err(); err != nil {
+		return imported, skipped, err
+	}
+	return imported, skipped, db.Save()
+}
+
+// splitIgnoreLine finds the colon separating two existing file paths in a legacy ignore list line.
+func splitIgnoreLine(line string) (string, string, bool) {
+	for i := range len(line) {
+		if line[i] != ':' {
+			continue
+		}
+		if path1, path2 := line[:i], line[i+1:]; FileExists(path1) && FileExists(path2) {
+			return path1, path2, true
+		}
+	}
+	return "", "", false
+}
@@ -0,0 +1,47 @@
+package util
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestIgnoreDBPruneKeepsRenamedFiles(t *testing.T) {
+	dir := t.TempDir()
+	paths := map[string]string{}
+	for _, name := range []string{"a.png", "b.png", "c.png", "d.png"} {
+		paths[name] = filepath.Join(dir, name)
+		if err := os.WriteFile(paths[name], []byte("content of "+name), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	db, err := OpenIgnoreDB(filepath.Join(dir, "ignored_pairs.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Add(paths["a.png"], paths["b.png"]); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Add(paths["c.png"], paths["d.png"]); err != nil {
+		t.Fatal(err)
+	}
+
+	// a.png is renamed, d.png is deleted
+	renamed := filepath.Join(dir, "renamed.png")
+	if err := os.Rename(paths["a.png"], renamed); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Remove(paths["d.png"]); err != nil {
+		t.Fatal(err)
+	}
+
+	if removed := db.Prune([]string{renamed, paths["b.png"], paths["c.png"]}); removed != 1 {
+		t.Errorf("pruned %d pairs, want 1", removed)
+	}
+	if !db.Contains(renamed, paths["b.png"]) {
+		t.Error("pair with a renamed file was pruned")
+	}
+	if db.Len() != 1 {
+		t.Errorf("%d pairs left, want 1", db.Len())
+	}
+}
@@ -0,0 +1,42 @@
+package util
+
+import (
+	"fmt"
+	"image"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/disintegration/imaging"
+)
+
+// JXLDecoderCommand is the external tool used to decode JPEG XL files, set with the -jxl-decoder flag.
+// There is no in-process decoder, so without it JPEG XL files cannot be opened.
+// It is invoked as `<command> <input> <output.png>`. An empty value disables JPEG XL support.
+var JXLDecoderCommand = "djxl"
+
+// loadJXL converts the JPEG XL file to PNG inside a unique temporary directory
+// using JXLDecoderCommand and decodes the result into memory.
+func loadJXL(path string) (image.Image, error) {
+	if JXLDecoderCommand == "" {
+		return nil, fmt.Errorf("cannot decode JPEG XL file %s: decoding needs an external decoder, set -jxl-decoder to djxl or a compatible command", path)
+	}
+	tool, err := exec.LookPath(JXLDecoderCommand)
+	if err != nil {
+		return nil, fmt.Errorf("cannot decode JPEG XL file %s: the decoder %q was not found, install djxl (libjxl) or point -jxl-decoder at a compatible command: %w", path, JXLDecoderCommand, err)
+	}
+
+	tmpDir, err := os.MkdirTemp("", "imgcomp-jxl-*")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(tmpDir)
+
+	tmpFile := filepath.Join(tmpDir, "decoded.png")
+	output, err := exec.Command(tool, path, tmpFile).CombinedOutput()
+	if err != nil {
+		return nil, fmt.Errorf("%s failed to decode %s: %w: %s", JXLDecoderCommand, path, err, strings.TrimSpace(string(output)))
+	}
+	return imaging.Open(tmpFile)
+}
@@ -0,0 +1,19 @@
+package util
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestLoadJXLErrorNamesTheFlag(t *testing.T) {
+	path := writeTestFile(t, "image.jxl", []byte{0xff, 0x0a})
+	defer func(command string) { JXLDecoderCommand = command }(JXLDecoderCommand)
+
+	for _, command := range []string{"", "imgcomp-missing-jxl-decoder"} {
+		JXLDecoderCommand = command
+		_, err := LoadImage(path)
+		if err == nil || !strings.Contains(err.Error(), "-jxl-decoder") {
+			t.Errorf("decoder %q: error %v does not name -jxl-decoder", command, err)
+		}
+	}
+}
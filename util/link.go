@@ -0,0 +1,114 @@
+package util
+
+import (
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"os"
+	"path/filepath"
+	"syscall"
+)
+
+// LinkMode is how a replaced file is pointed at the file it duplicates.
+type LinkMode int
+
+const (
+	// Hardlink makes both paths refer to the same file.
+	Hardlink LinkMode = iota
+	// Symlink makes the replaced path a symbolic link to the absolute path of the kept file.
+	Symlink
+	// Reflink makes the replaced path a copy-on-write clone of the kept file, on file systems supporting it.
+	Reflink
+	// Copy makes the replaced path a plain copy of the kept file.
+	Copy
+)
+
+func (m LinkMode) String() string {
+	switch m {
+	case Hardlink:
+		return "hardlink"
+	case Symlink:
+		return "symlink"
+	case Reflink:
+		return "reflink"
+	case Copy:
+		return "copy"
+	}
+	return "unknown"
+}
+
+// ParseLinkMode parses the name of a link mode as returned by String.
+func ParseLinkMode(name string) (LinkMode, error) {
+	for _, mode := range []LinkMode{Hardlink, Symlink, Reflink, Copy} {
+		if mode.String() == name {
+			return mode, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown link mode %q, expected hardlink, symlink, reflink or copy", name)
+}
+
+// LinkFile creates target as a link to source using the given mode.
+// Hardlinks and reflinks that cannot be created, such as across file systems, fall back to a copy.
+// The target is created under a temporary name and renamed into place, so a failure leaves no partial file behind.
+// It returns the mode that was actually used.
+func LinkFile(source, target string, mode LinkMode) (LinkMode, error) {
+	source, err := filepath.Abs(source)
+	if err != nil {
+		return mode, err
+	}
+
+	used := mode
+	tmp, err := createTemp(target, func(tmp string) error {
+		switch mode {
+		case Hardlink:
+			return os.Link(source, tmp)
+		case Symlink:
+			return os.Symlink(source, tmp)
+		case Reflink:
+			return reflinkFile(source, tmp)
+		}
+		return copyFile(source, tmp)
+	})
+	if err != nil && mode != Symlink && mode != Copy && linkUnsupported(err) {
+		used = Copy
+		tmp, err = createTemp(target, func(tmp string) error {
+			return copyFile(source, tmp)
+		})
+	}
+	if err != nil {
+		return used, err
+	}
+
+	if err := os.Rename(tmp, target); err != nil {
+		os.Remove(tmp)
+		return used, err
+	}
+	return used, nil
+}
+
+// createTemp calls create with an unused temporary name next to target and returns that name.
+// Names that are taken, for example by a file left behind by a crashed run, are skipped for another random name.
+func createTemp(target string, create func(tmp string) error) (string, error) {
+	for try := 0; ; try++ {
+		tmp := filepath.Join(filepath.Dir(target), fmt.Sprintf(".%s.imgcomp-%08x", filepath.Base(target), rand.Uint32()))
+		err := create(tmp)
+		if errors.Is(err, os.ErrExist) && try < 100 {
+			continue
+		}
+		if err != nil && !errors.Is(err, os.ErrExist) {
+			os.Remove(tmp)
+		}
+		return tmp, err
+	}
+}
+
+// linkUnsupported reports whether linking failed because the file system cannot link the files,
+// as opposed to failures a copy would run into as well.
+func linkUnsupported(err error) bool {
+	return errors.Is(err, syscall.EXDEV) ||
+		errors.Is(err, errors.ErrUnsupported) ||
+		errors.Is(err, syscall.EOPNOTSUPP) ||
+		errors.Is(err, syscall.EINVAL) ||
+		errors.Is(err, syscall.EPERM) ||
+		errors.Is(err, syscall.EMLINK)
+}
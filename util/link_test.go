@@ -0,0 +1,152 @@
+package util
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// linkTestFiles creates a source file and an existing target to be replaced in dir.
+func linkTestFiles(t *testing.T, dir string) (string, string) {
+	t.Helper()
+	source := filepath.Join(dir, "kept.png")
+	target := filepath.Join(dir, "replaced.png")
+	if err := os.WriteFile(source, []byte("kept"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(target, []byte("replaced"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return source, target
+}
+
+// checkNoTempFiles fails if LinkFile left temporary files in dir.
+func checkNoTempFiles(t *testing.T, dir string) {
+	t.Helper()
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, entry := range entries {
+		if strings.Contains(entry.Name(), ".imgcomp-") {
+			t.Errorf("temporary file %s left behind", entry.Name())
+		}
+	}
+}
+
+func TestLinkFile(t *testing.T) {
+	for _, mode := range []LinkMode{Hardlink, Symlink, Reflink, Copy} {
+		t.Run(mode.String(), func(t *testing.T) {
+			dir := t.TempDir()
+			source, target := linkTestFiles(t, dir)
+			modTime := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+			if err := os.Chtimes(source, modTime, modTime); err != nil {
+				t.Fatal(err)
+			}
+
+			used, err := LinkFile(source, target, mode)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if data, err := os.ReadFile(target); err != nil || string(data) != "kept" {
+				t.Fatalf("target reads %q, %v", data, err)
+			}
+			checkNoTempFiles(t, dir)
+
+			sourceInfo, _ := os.Stat(source)
+			targetInfo, err := os.Lstat(target)
+			if err != nil {
+				t.Fatal(err)
+			}
+			switch mode {
+			case Hardlink:
+				if used != Hardlink || !os.SameFile(sourceInfo, targetInfo) {
+					t.Errorf("used %v, same file %v", used, os.SameFile(sourceInfo, targetInfo))
+				}
+			case Symlink:
+				if link, err := os.Readlink(target); used != Symlink || err != nil || link != source {
+					t.Errorf("used %v, link to %q, %v", used, link, err)
+				}
+			case Reflink:
+				// Reflinks need a copy-on-write file system, elsewhere the file is copied
+				if used != Reflink && used != Copy {
+					t.Errorf("used %v", used)
+				}
+				if os.SameFile(sourceInfo, targetInfo) || targetInfo.Mode()&os.ModeSymlink != 0 {
+					t.Error("reflink is not a file of its own")
+				}
+			case Copy:
+				if used != Copy || os.SameFile(sourceInfo, targetInfo) || !targetInfo.ModTime().Equal(modTime) {
+					t.Errorf("used %v, same file %v, modified %v", used, os.SameFile(sourceInfo, targetInfo), targetInfo.ModTime())
+				}
+			}
+		})
+	}
+}
+
+func TestLinkFileFallsBackToCopyAcrossFileSystems(t *testing.T) {
+	dir := t.TempDir()
+	source, _ := linkTestFiles(t, dir)
+	other, err := os.MkdirTemp("/dev/shm", "imgcomp-test-")
+	if err != nil {
+		t.Skip("no second file system to link across:", err)
+	}
+	t.Cleanup(func() { os.RemoveAll(other) })
+	sourceDevice, _ := deviceID(dir)
+	if otherDevice, err := deviceID(other); err != nil || otherDevice == sourceDevice {
+		t.Skip("/dev/shm is on the same file system as the temporary directory")
+	}
+
+	for _, mode := range []LinkMode{Hardlink, Reflink} {
+		target := filepath.Join(other, mode.String()+".png")
+		used, err := LinkFile(source, target, mode)
+		if err != nil {
+			t.Fatalf("%v: %v", mode, err)
+		}
+		if used != Copy {
+			t.Errorf("%v across file systems used %v, want copy", mode, used)
+		}
+		if data, err := os.ReadFile(target); err != nil || string(data) != "kept" {
+			t.Errorf("%v: target reads %q, %v", mode, data, err)
+		}
+	}
+	checkNoTempFiles(t, other)
+}
+
+func TestCreateTempSkipsTakenNames(t *testing.T) {
+	dir := t.TempDir()
+	_, target := linkTestFiles(t, dir)
+
+	var names []string
+	tmp, err := createTemp(target, func(tmp string) error {
+		names = append(names, tmp)
+		if len(names) == 1 {
+			// The first name is taken by a file left behind by a crashed run
+			if err := os.WriteFile(tmp, nil, 0644); err != nil {
+				return err
+			}
+		}
+		return os.Link(target, tmp)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(names) != 2 || names[0] == names[1] || tmp != names[1] || filepath.Dir(tmp) != dir {
+		t.Errorf("tried %v, used %s", names, tmp)
+	}
+	if !FileExists(names[0]) {
+		t.Error("leftover file removed")
+	}
+
+	// Errors other than a taken name are returned without retrying
+	tries := 0
+	if _, err := createTemp(target, func(string) error {
+		tries++
+		return os.ErrPermission
+	}); !errors.Is(err, os.ErrPermission) || tries != 1 {
+		t.Errorf("got %v after %d tries", err, tries)
+	}
+}
@@ -0,0 +1,197 @@
+package util
+
+import (
+	"fmt"
+	"image"
+	"math"
+	"math/bits"
+	"sort"
+
+	"github.com/disintegration/imaging"
+)
+
+// HashAlgorithm defines the type for perceptual hashing algorithms.
+type HashAlgorithm int
+
+const (
+	// AverageHash compares each pixel of a downscaled image against the mean.
+	AverageHash HashAlgorithm = iota
+	// DifferenceHash compares horizontally adjacent pixels of a downscaled image.
+	DifferenceHash
+	// PerceptualHash compares low-frequency DCT coefficients against their median.
+	PerceptualHash
+)
+
+// DefaultHashSize is the hash side length used by Czkawka, producing 64-bit hashes.
+const DefaultHashSize = 8
+
+// MaxHashSize is the largest supported hash side length.
+// The pHash DCT runs over a plane four times as wide and takes cubic time in its side length.
+const MaxHashSize = 64
+
+// CheckHashSize rejects hash side lengths that ComputeHash does not support.
+// The pHash median needs at least one coefficient besides the DC coefficient, so the smallest size is 2.
+func CheckHashSize(size int) error {
+	if size < 2 || size > MaxHashSize {
+		return fmt.Errorf("hash size must be between 2 and %d, got %d", MaxHashSize, size)
+	}
+	return nil
+}
+
+// HashAlgorithms lists all supported perceptual hashing algorithms.
+var HashAlgorithms = []HashAlgorithm{AverageHash, DifferenceHash, PerceptualHash}
+
+func (a HashAlgorithm) String() string {
+	switch a {
+	case AverageHash:
+		return "aHash"
+	case DifferenceHash:
+		return "dHash"
+	case PerceptualHash:
+		return "pHash"
+	}
+	return "unknown"
+}
+
+// ImageHash is a perceptual hash of hashSize*hashSize bits.
+type ImageHash struct {
+	Algo HashAlgorithm
+	Size int
+	Bits []uint64
+}
+
+// HashDistance is the Hamming distance between two hashes produced by the same algorithm.
+type HashDistance struct {
+	Algo     HashAlgorithm
+	Distance int
+	BitCount int
+}
+
+// newImageHash creates an empty hash able to hold size*size bits.
+func newImageHash(algo HashAlgorithm, size int) ImageHash {
+	return ImageHash{
+		Algo: algo,
+		Size: size,
+		Bits: make([]uint64, (size*size+63)/64),
+	}
+}
+
+// setBit sets the i-th bit of the hash.
+func (h ImageHash) setBit(i int) {
+	h.Bits[i/64] |= 1 << (i % 64)
+}
+
+// hashPixels downscales the image to w x h and returns its luminance.
+func hashPixels(img image.Image, w, h int) []float64 {
+	return luminance(imaging.Resize(img, w, h, imaging.Lanczos))
+}
+
+// ComputeHash computes a perceptual hash of the image with the given side length.
+func ComputeHash(img image.Image, algo HashAlgorithm, hashSize int) ImageHash {
+	hash := newImageHash(algo, hashSize)
+
+	switch algo {
+	case AverageHash:
+		pixels := hashPixels(img, hashSize, hashSize)
+		avg := mean(pixels)
+		for i, v := range pixels {
+			if v > avg {
+				hash.setBit(i)
+			}
+		}
+
+	case DifferenceHash:
+		pixels := hashPixels(img, hashSize+1, hashSize)
+		for y := 0; y < hashSize; y++ {
+			for x := 0; x < hashSize; x++ {
+				if pixels[y*(hashSize+1)+x] < pixels[y*(hashSize+1)+x+1] {
+					hash.setBit(y*hashSize + x)
+				}
+			}
+		}
+
+	case PerceptualHash:
+		// The DCT is computed over a 4x larger image and only the lowest frequencies are kept
+		side := hashSize * 4
+		coefficients := dct2D(hashPixels(img, side, side), side)
+
+		lowFrequencies := make([]float64, 0, hashSize*hashSize)
+		for y := 0; y < hashSize; y++ {
+			lowFrequencies = append(lowFrequencies, coefficients[y*side:y*side+hashSize]...)
+		}
+
+		// The DC coefficient only reflects overall brightness, leave it out of the median
+		sorted := append([]float64(nil), lowFrequencies[1:]...)
+		sort.Float64s(sorted)
+		median := sorted[len(sorted)/2]
+
+		for i, v := range lowFrequencies {
+			if v > median {
+				hash.setBit(i)
+			}
+		}
+	}
+
+	return hash
+}
+
+// dct2D computes the two-dimensional type-II discrete cosine transform of a square plane.
+func dct2D(src []float64, n int) []float64 {
+	cosines := make([]float64, n*n)
+	for k := 0; k < n; k++ {
+		for i := 0; i < n; i++ {
+			cosines[k*n+i] = math.Cos(math.Pi / float64(n) * (float64(i) + 0.5) * float64(k))
+		}
+	}
+
+	rows := make([]float64, n*n)
+	for y := 0; y < n; y++ {
+		for k := 0; k < n; k++ {
+			var sum float64
+			for x := 0; x < n; x++ {
+				sum += src[y*n+x] * cosines[k*n+x]
+			}
+			rows[y*n+k] = sum
+		}
+	}
+
+	dst := make([]float64, n*n)
+	for x := 0; x < n; x++ {
+		for k := 0; k < n; k++ {
+			var sum float64
+			for y := 0; y < n; y++ {
+				sum += rows[y*n+x] * cosines[k*n+y]
+			}
+			dst[k*n+x] = sum
+		}
+	}
+	return dst
+}
+
+// HammingDistance returns the number of differing bits between two hashes.
+// Both hashes must have been produced by the same algorithm with the same size.
+func HammingDistance(a, b ImageHash) (int, error) {
+	if a.Algo != b.Algo || a.Size != b.Size {
+		return 0, fmt.Errorf("cannot compare %s hash of size %d with %s hash of size %d", a.Algo, a.Size, b.Algo, b.Size)
+	}
+	distance := 0
+	for i := range a.Bits {
+		distance += bits.OnesCount64(a.Bits[i] ^ b.Bits[i])
+	}
+	return distance, nil
+}
+
+// CompareHashes computes the Hamming distance between the two images for every hashing algorithm.
+func CompareHashes(img1, img2 image.Image, hashSize int) []HashDistance {
+	distances := make([]HashDistance, 0, len(HashAlgorithms))
+	for _, algo := range HashAlgorithms {
+		// Both hashes share the algorithm and size, so comparing them cannot fail
+		distance, _ := HammingDistance(ComputeHash(img1, algo, hashSize), ComputeHash(img2, algo, hashSize))
+		distances = append(distances, HashDistance{
+			Algo:     algo,
+			Distance: distance,
+			BitCount: hashSize * hashSize,
+		})
+	}
+	return distances
+}
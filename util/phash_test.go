@@ -0,0 +1,92 @@
+package util
+
+import (
+	"bytes"
+	"image"
+	"image/jpeg"
+	"math/rand"
+	"testing"
+
+	"github.com/disintegration/imaging"
+)
+
+// unrelatedImage returns a smooth random image that shares no content with texturedImage.
+func unrelatedImage(width, height int) *image.NRGBA {
+	noise := randomNRGBA(rand.New(rand.NewSource(2)), image.Rect(0, 0, 40, 30))
+	return imaging.Resize(noise, width, height, imaging.Lanczos)
+}
+
+// reencoded returns the image after a round trip through JPEG at the given quality.
+func reencoded(t *testing.T, img image.Image, quality int) image.Image {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, img, &jpeg.Options{Quality: quality}); err != nil {
+		t.Fatal(err)
+	}
+	decoded, err := jpeg.Decode(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return decoded
+}
+
+func TestCompareHashesOfCopies(t *testing.T) {
+	img := texturedImage(320, 240)
+	copies := map[string]image.Image{
+		"re-encoded": reencoded(t, img, 70),
+		"downscaled": imaging.Resize(img, 160, 120, imaging.Box),
+		"brightened": imaging.AdjustBrightness(img, 10),
+	}
+
+	for _, hashSize := range []int{DefaultHashSize, 16} {
+		for _, d := range CompareHashes(img, imaging.Clone(img), hashSize) {
+			if d.Distance != 0 || d.BitCount != hashSize*hashSize {
+				t.Errorf("size %d, identical copy: %s distance %d/%d, want 0", hashSize, d.Algo, d.Distance, d.BitCount)
+			}
+		}
+		for name, copied := range copies {
+			for _, d := range CompareHashes(img, copied, hashSize) {
+				if d.Distance > d.BitCount/10 {
+					t.Errorf("size %d, %s copy: %s distance %d/%d, want at most a tenth of the bits", hashSize, name, d.Algo, d.Distance, d.BitCount)
+				}
+			}
+		}
+		for _, d := range CompareHashes(img, unrelatedImage(320, 240), hashSize) {
+			if d.Distance < d.BitCount/4 {
+				t.Errorf("size %d, unrelated image: %s distance %d/%d, want at least a quarter of the bits", hashSize, d.Algo, d.Distance, d.BitCount)
+			}
+		}
+	}
+}
+
+func TestHammingDistanceRejectsMismatchedHashes(t *testing.T) {
+	img := texturedImage(64, 48)
+	hash := ComputeHash(img, PerceptualHash, 8)
+	if _, err := HammingDistance(hash, ComputeHash(img, PerceptualHash, 16)); err == nil {
+		t.Error("compared hashes of different sizes")
+	}
+	if _, err := HammingDistance(hash, ComputeHash(img, AverageHash, 8)); err == nil {
+		t.Error("compared hashes of different algorithms")
+	}
+}
+
+func TestCheckHashSize(t *testing.T) {
+	for _, size := range []int{-8, 0, 1, MaxHashSize + 1, 1 << 20} {
+		if err := CheckHashSize(size); err == nil {
+			t.Errorf("hash size %d accepted", size)
+		}
+	}
+	img := texturedImage(64, 48)
+	for _, size := range []int{2, DefaultHashSize, MaxHashSize} {
+		if err := CheckHashSize(size); err != nil {
+			t.Errorf("hash size %d rejected: %v", size, err)
+			continue
+		}
+		// Every supported size can be hashed with every algorithm
+		for _, d := range CompareHashes(img, img, size) {
+			if d.Distance != 0 || d.BitCount != size*size {
+				t.Errorf("size %d: %s distance %d/%d", size, d.Algo, d.Distance, d.BitCount)
+			}
+		}
+	}
+}
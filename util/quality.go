@@ -0,0 +1,383 @@
+package util
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"image"
+	"math"
+	"os"
+	"strings"
+
+	"github.com/disintegration/imaging"
+)
+
+// Longest side of the copy sharpness is measured on, so that images of different sizes compare fairly.
+const sharpnessWorkSize = 512
+
+// Weights of the criteria used to suggest which image to keep.
+const (
+	resolutionWeight  = 3.0
+	formatWeight      = 2.0
+	jpegQualityWeight = 2.0
+	sharpnessWeight   = 2.0
+	fileSizeWeight    = 1.0
+	metadataWeight    = 1.0
+)
+
+// Smallest relative differences that count as one image being better.
+const (
+	resolutionMargin  = 0.01
+	sharpnessMargin   = 0.10
+	fileSizeMargin    = 0.05
+	jpegQualityMargin = 3
+)
+
+// Standard IJG luminance quantization table at quality 50, which libjpeg scales for other qualities.
+var standardLuminanceTable = [64]int{
+	16, 11, 10, 16, 24, 40, 51, 61,
+	12, 12, 14, 19, 26, 58, 60, 55,
+	14, 13, 16, 24, 40, 57, 69, 56,
+	14, 17, 22, 29, 51, 87, 80, 62,
+	18, 22, 37, 56, 68, 109, 103, 77,
+	24, 35, 55, 64, 81, 104, 113, 92,
+	49, 64, 78, 87, 103, 121, 120, 101,
+	72, 92, 95, 98, 112, 100, 103, 99,
+}
+
+// QualityReport holds the quality signals of an image used to suggest which of two images to keep.
+type QualityReport struct {
+	Width       int
+	Height      int
+	FileSize    int64
+	Format      string
+	Lossless    bool
+	JPEGQuality int      // Estimated libjpeg quality 1-100, 0 if not a JPEG or unknown
+	Sharpness   float64  // Variance of the Laplacian of the luminance, higher is sharper
+	Metadata    []string // Kinds of metadata found, such as EXIF, ICC profile and XMP
+}
+
+// AnalyzeQuality collects the quality signals of a decoded image and its file.
+func AnalyzeQuality(path string, img image.Image) QualityReport {
+	report := QualityReport{
+		Width:     img.Bounds().Dx(),
+		Height:    img.Bounds().Dy(),
+		Format:    ImageFormat(path),
+		Sharpness: sharpness(img),
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return report
+	}
+	report.FileSize = int64(len(data))
+
+	switch report.Format {
+	case "jpeg":
+		report.JPEGQuality = estimateJPEGQuality(data)
+	case "png", "gif", "bmp", "tiff":
+		report.Lossless = true
+	case "webp":
+		report.Lossless = webpLossless(data)
+	}
+	report.Metadata = metadataKinds(data)
+	return report
+}
+
+// sharpness returns the variance of the Laplacian of the image's luminance, a common blur metric.
+func sharpness(img image.Image) float64 {
+	b := img.Bounds()
+	if max(b.Dx(), b.Dy()) > sharpnessWorkSize {
+		img = imaging.Fit(img, sharpnessWorkSize, sharpnessWorkSize, imaging.Box)
+		b = img.Bounds()
+	}
+	w, h := b.Dx(), b.Dy()
+	if w < 3 || h < 3 {
+		return 0
+	}
+	luma := luminance(img)
+
+	var sum, sumSquares float64
+	for y := 1; y < h-1; y++ {
+		for x := 1; x < w-1; x++ {
+			i := y*w + x
+			l := luma[i-w] + luma[i+w] + luma[i-1] + luma[i+1] - 4*luma[i]
+			sum += l
+			sumSquares += l * l
+		}
+	}
+	n := float64((w - 2) * (h - 2))
+	mean := sum / n
+	return sumSquares/n - mean*mean
+}
+
+// estimateJPEGQuality estimates the libjpeg quality setting from the luminance quantization table,
+// picking the quality whose scaled standard table is closest in total to the one in the file.
+func estimateJPEGQuality(data []byte) int {
+	table := jpegLuminanceTable(data)
+	if table == nil {
+		return 0
+	}
+	actual := 0
+	for _, v := range table {
+		actual += v
+	}
+
+	best, bestDiff := 0, math.MaxInt
+	for quality := 1; quality <= 100; quality++ {
+		scale := 200 - 2*quality
+		if quality < 50 {
+			scale = 5000 / quality
+		}
+		expected := 0
+		for _, v := range standardLuminanceTable {
+			expected += min(max((v*scale+50)/100, 1), 255)
+		}
+		if diff := absInt(expected - actual); diff < bestDiff {
+			best, bestDiff = quality, diff
+		}
+	}
+	return best
+}
+
+func absInt(x int) int {
+	if x < 0 {
+		return -x
+	}
+	return x
+}
+
+// jpegLuminanceTable returns quantization table 0 from the DQT segments of a JPEG file.
+func jpegLuminanceTable(data []byte) []int {
+	var table []int
+	walkJPEGSegments(data, func(marker byte, segment []byte) {
+		if marker != 0xDB {
+			return
+		}
+		for len(segment) > 0 {
+			precision, id := segment[0]>>4, segment[0]&0x0F
+			size := 64
+			if precision == 1 {
+				size = 128
+			}
+			if len(segment) < 1+size {
+				return
+			}
+			if id == 0 {
+				table = make([]int, 64)
+				for i := range table {
+					if precision == 1 {
+						table[i] = int(binary.BigEndian.Uint16(segment[1+2*i:]))
+					} else {
+						table[i] = int(segment[1+i])
+					}
+				}
+			}
+			segment = segment[1+size:]
+		}
+	})
+	return table
+}
+
+// walkJPEGSegments calls fn with every marker segment before the start of scan.
+func walkJPEGSegments(data []byte, fn func(marker byte, segment []byte)) {
+	if len(data) < 2 || data[0] != 0xFF || data[1] != 0xD8 {
+		return
+	}
+	for pos := 2; pos+4 <= len(data) && data[pos] == 0xFF; {
+		marker := data[pos+1]
+		length := int(binary.BigEndian.Uint16(data[pos+2:]))
+		if marker == 0xDA || length < 2 || pos+2+length > len(data) {
+			return
+		}
+		fn(marker, data[pos+4:pos+2+length])
+		pos += 2 + length
+	}
+}
+
+// walkWebPChunks calls fn with every top-level chunk of a WebP file.
+func walkWebPChunks(data []byte, fn func(fourCC string, chunk []byte)) {
+	if len(data) < 12 || string(data[0:4]) != "RIFF" || string(data[8:12]) != "WEBP" {
+		return
+	}
+	for pos := 12; pos+8 <= len(data); {
+		size := int(binary.LittleEndian.Uint32(data[pos+4:]))
+		if pos+8+size > len(data) {
+			return
+		}
+		fn(string(data[pos:pos+4]), data[pos+8:pos+8+size])
+		pos += 8 + size + size&1
+	}
+}
+
+// webpLossless reports whether a WebP file is VP8L encoded, checking the frames of animations as well.
+func webpLossless(data []byte) bool {
+	lossless := false
+	walkWebPChunks(data, func(fourCC string, chunk []byte) {
+		switch fourCC {
+		case "VP8L":
+			lossless = true
+		case "ANMF":
+			// The frame header is 16 bytes, followed by the frame's own chunks
+			lossless = lossless || len(chunk) >= 20 && string(chunk[16:20]) == "VP8L"
+		}
+	})
+	return lossless
+}
+
+// metadataKinds lists the kinds of metadata embedded in a JPEG, PNG or WebP file.
+func metadataKinds(data []byte) []string {
+	found := make(map[string]bool)
+	switch {
+	case len(data) >= 2 && data[0] == 0xFF && data[1] == 0xD8:
+		walkJPEGSegments(data, func(marker byte, segment []byte) {
+			switch {
+			case marker == 0xE1 && bytes.HasPrefix(segment, []byte("Exif\x00")):
+				found["EXIF"] = true
+			case marker == 0xE1 && bytes.HasPrefix(segment, []byte("http://ns.adobe.com/xap/1.0/")):
+				found["XMP"] = true
+			case marker == 0xE2 && bytes.HasPrefix(segment, []byte("ICC_PROFILE")):
+				found["ICC profile"] = true
+			}
+		})
+	case bytes.HasPrefix(data, pngSignature):
+		chunks, err := readPNGChunks(data)
+		if err != nil {
+			break
+		}
+		for _, chunk := range chunks {
+			switch {
+			case chunk.Type == "eXIf":
+				found["EXIF"] = true
+			case chunk.Type == "iCCP":
+				found["ICC profile"] = true
+			case chunk.Type == "iTXt" && bytes.HasPrefix(chunk.Data, []byte("XML:com.adobe.xmp")):
+				found["XMP"] = true
+			}
+		}
+	default:
+		walkWebPChunks(data, func(fourCC string, chunk []byte) {
+			switch fourCC {
+			case "EXIF":
+				found["EXIF"] = true
+			case "ICCP":
+				found["ICC profile"] = true
+			case "XMP ":
+				found["XMP"] = true
+			}
+		})
+	}
+
+	var kinds []string
+	for _, kind := range []string{"EXIF", "ICC profile", "XMP"} {
+		if found[kind] {
+			kinds = append(kinds, kind)
+		}
+	}
+	return kinds
+}
+
+// KeeperSuggestion is the recommendation of which of two images to keep.
+type KeeperSuggestion struct {
+	Keep    int // 1 or 2, 0 if neither image is clearly better
+	Score1  float64
+	Score2  float64
+	Reasons []string // Criteria that favoured one of the images
+}
+
+// Summary describes the suggestion and its reasoning in a single line.
+func (s KeeperSuggestion) Summary() string {
+	if s.Keep == 0 {
+		return "No clear keeper, the images are of similar quality"
+	}
+	return fmt.Sprintf("Suggested keeper: image %d (score %.0f vs %.0f): %s",
+		s.Keep, max(s.Score1, s.Score2), min(s.Score1, s.Score2), strings.Join(s.Reasons, "; "))
+}
+
+// SuggestKeeper ranks two images by resolution, format, JPEG quality, sharpness, file size and metadata.
+// Every criterion that clearly favours one image adds its weight to that image's score.
+func SuggestKeeper(r1, r2 QualityReport) KeeperSuggestion {
+	var s KeeperSuggestion
+	award := func(winner int, weight float64, reason string) {
+		if winner == 1 {
+			s.Score1 += weight
+		} else {
+			s.Score2 += weight
+		}
+		s.Reasons = append(s.Reasons, fmt.Sprintf("image %d %s", winner, reason))
+	}
+
+	pixels1, pixels2 := float64(r1.Width*r1.Height), float64(r2.Width*r2.Height)
+	if winner, ok := clearlyGreater(pixels1, pixels2, resolutionMargin); ok {
+		award(winner, resolutionWeight, fmt.Sprintf("has the higher resolution (%dx%d vs %dx%d)",
+			pick(winner, r1.Width, r2.Width), pick(winner, r1.Height, r2.Height),
+			pick(winner, r2.Width, r1.Width), pick(winner, r2.Height, r1.Height)))
+	}
+
+	if r1.Lossless != r2.Lossless {
+		winner := 2
+		if r1.Lossless {
+			winner = 1
+		}
+		award(winner, formatWeight, fmt.Sprintf("uses a lossless format (%s vs %s)",
+			pick(winner, r1.Format, r2.Format), pick(winner, r2.Format, r1.Format)))
+	}
+
+	if r1.JPEGQuality > 0 && r2.JPEGQuality > 0 && absInt(r1.JPEGQuality-r2.JPEGQuality) >= jpegQualityMargin {
+		winner := 2
+		if r1.JPEGQuality > r2.JPEGQuality {
+			winner = 1
+		}
+		award(winner, jpegQualityWeight, fmt.Sprintf("was saved at a higher JPEG quality (~%d vs ~%d)",
+			pick(winner, r1.JPEGQuality, r2.JPEGQuality), pick(winner, r2.JPEGQuality, r1.JPEGQuality)))
+	}
+
+	if winner, ok := clearlyGreater(r1.Sharpness, r2.Sharpness, sharpnessMargin); ok {
+		award(winner, sharpnessWeight, fmt.Sprintf("is sharper (%.0f vs %.0f)",
+			pick(winner, r1.Sharpness, r2.Sharpness), pick(winner, r2.Sharpness, r1.Sharpness)))
+	}
+
+	// A larger file only hints at less compression when the formats match
+	if r1.Format == r2.Format {
+		if winner, ok := clearlyGreater(float64(r1.FileSize), float64(r2.FileSize), fileSizeMargin); ok {
+			award(winner, fileSizeWeight, fmt.Sprintf("is the larger file (%s vs %s bytes)",
+				FormatIntWithSpaces(pick(winner, r1.FileSize, r2.FileSize)),
+				FormatIntWithSpaces(pick(winner, r2.FileSize, r1.FileSize))))
+		}
+	}
+
+	if len(r1.Metadata) != len(r2.Metadata) {
+		winner := 2
+		if len(r1.Metadata) > len(r2.Metadata) {
+			winner = 1
+		}
+		award(winner, metadataWeight, fmt.Sprintf("has more metadata (%s)",
+			strings.Join(pick(winner, r1.Metadata, r2.Metadata), ", ")))
+	}
+
+	switch {
+	case s.Score1 > s.Score2:
+		s.Keep = 1
+	case s.Score2 > s.Score1:
+		s.Keep = 2
+	}
+	return s
+}
+
+// clearlyGreater reports which of two values is larger by more than the relative margin.
+func clearlyGreater(a, b, margin float64) (int, bool) {
+	switch {
+	case a > b*(1+margin):
+		return 1, true
+	case b > a*(1+margin):
+		return 2, true
+	}
+	return 0, false
+}
+
+// pick returns a if winner is 1 and b otherwise.
+func pick[T any](winner int, a, b T) T {
+	if winner == 1 {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,71 @@
+package util
+
+import (
+	"bytes"
+	"image/jpeg"
+	"strings"
+	"testing"
+
+	"github.com/disintegration/imaging"
+)
+
+func TestEstimateJPEGQuality(t *testing.T) {
+	img := texturedImage(64, 48)
+	for _, quality := range []int{5, 10, 25, 50, 60, 75, 85, 90, 95} {
+		var buf bytes.Buffer
+		if err := jpeg.Encode(&buf, img, &jpeg.Options{Quality: quality}); err != nil {
+			t.Fatal(err)
+		}
+		if got := estimateJPEGQuality(buf.Bytes()); got != quality {
+			t.Errorf("quality %d estimated as %d", quality, got)
+		}
+	}
+
+	// Near 100 most table entries are clamped to 1, so neighbouring qualities share the same tables
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, img, &jpeg.Options{Quality: 100}); err != nil {
+		t.Fatal(err)
+	}
+	if got := estimateJPEGQuality(buf.Bytes()); got < 98 {
+		t.Errorf("quality 100 estimated as %d", got)
+	}
+
+	if got := estimateJPEGQuality([]byte("not a jpeg")); got != 0 {
+		t.Errorf("estimated quality %d for data without a DQT segment", got)
+	}
+}
+
+func TestAnalyzeQualityReadsJPEGQuality(t *testing.T) {
+	img := texturedImage(64, 48)
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, img, &jpeg.Options{Quality: 80}); err != nil {
+		t.Fatal(err)
+	}
+	path := writeTestFile(t, "photo.jpg", buf.Bytes())
+
+	report := AnalyzeQuality(path, img)
+	if report.Format != "jpeg" || report.Lossless || report.JPEGQuality != 80 || report.FileSize != int64(buf.Len()) {
+		t.Errorf("report %+v", report)
+	}
+}
+
+func TestSharpnessFavoursTheUnblurredImage(t *testing.T) {
+	img := texturedImage(320, 240)
+	previous := sharpness(img)
+	for _, sigma := range []float64{0.5, 1, 2} {
+		blurred := sharpness(imaging.Blur(img, sigma))
+		if blurred >= previous {
+			t.Errorf("blur sigma %v: sharpness %v, want below %v", sigma, blurred, previous)
+		}
+		previous = blurred
+	}
+
+	// With everything else equal, sharpness alone decides the keeper
+	blurred := QualityReport{Width: 320, Height: 240, Format: "png", Lossless: true, Sharpness: sharpness(imaging.Blur(img, 1))}
+	sharp := blurred
+	sharp.Sharpness = sharpness(img)
+	suggestion := SuggestKeeper(blurred, sharp)
+	if suggestion.Keep != 2 || len(suggestion.Reasons) != 1 || !strings.Contains(suggestion.Reasons[0], "sharper") {
+		t.Errorf("suggestion %+v, want image 2 for being sharper", suggestion)
+	}
+}
@@ -0,0 +1,13 @@
+//go:build !linux
+
+package util
+
+import (
+	"errors"
+	"os"
+)
+
+// reflinkFile is only implemented on Linux, elsewhere LinkFile falls back to a copy.
+func reflinkFile(source, target string) error {
+	return &os.LinkError{Op: "reflink", Old: source, New: target, Err: errors.ErrUnsupported}
+}
@@ -0,0 +1,281 @@
+package util
+
+import (
+	"image"
+	"math"
+
+	"github.com/disintegration/imaging"
+)
+
+const (
+	registrationWorkSize   = 64   // Longest side of the image the coarse search runs on
+	registrationRefineSize = 1024 // Longest side up to which the match is refined before the last pass at full size
+	registrationMinScale   = 0.3  // Smallest crop, relative to the larger image, that is searched for
+	registrationScaleStep  = 0.02 // Scale step of the coarse search
+	registrationMinSize    = 8    // Smallest template side in pixels during the coarse search
+	registrationMaxError   = 10.0 // Largest mean luminance error (0-255) accepted as a match
+	registrationMaxCover   = 0.98 // Matches covering more of the larger image than this are not treated as crops
+)
+
+// Overlap describes the region two images have in common when one is a crop of the other.
+type Overlap struct {
+	Rect1        image.Rectangle // Area of img1 covered by the overlap
+	Rect2        image.Rectangle // Area of img2 covered by the overlap
+	CroppedImage int             // Which image (1 or 2) is the crop of the other
+	Scale        float64         // Size of the cropped image relative to the matching area of the other
+	Error        float64         // Mean luminance error (0-255) of the match
+}
+
+// lumaPlane is a grayscale copy of an image used for template matching.
+type lumaPlane struct {
+	pix  []float64
+	w, h int
+}
+
+// newLumaPlane resizes the image to w x h and converts it to luminance.
+func newLumaPlane(img image.Image, w, h int) lumaPlane {
+	if img.Bounds().Dx() != w || img.Bounds().Dy() != h {
+		img = imaging.Resize(img, w, h, imaging.Box)
+	}
+	return lumaPlane{pix: luminance(img), w: w, h: h}
+}
+
+// matchError returns the mean absolute error of the template placed at (ox, oy),
+// sampling every step-th pixel of the template.
+func (p lumaPlane) matchError(template lumaPlane, ox, oy, step int) float64 {
+	var sum float64
+	var count int
+	for y := 0; y < template.h; y += step {
+		row := (oy+y)*p.w + ox
+		for x := 0; x < template.w; x += step {
+			sum += math.Abs(p.pix[row+x] - template.pix[y*template.w+x])
+			count++
+		}
+	}
+	return sum / float64(count)
+}
+
+// sample resizes the plane to w x h with nearest neighbour sampling, keeping only every step-th pixel in both directions.
+func (p lumaPlane) sample(w, h, step int) lumaPlane {
+	samples := lumaPlane{w: (w + step - 1) / step, h: (h + step - 1) / step}
+	samples.pix = make([]float64, samples.w*samples.h)
+	for sy := 0; sy < samples.h; sy++ {
+		y := min(p.h-1, int((float64(sy*step)+0.5)*float64(p.h)/float64(h)))
+		for sx := 0; sx < samples.w; sx++ {
+			x := min(p.w-1, int((float64(sx*step)+0.5)*float64(p.w)/float64(w)))
+			samples.pix[sy*samples.w+sx] = p.pix[y*p.w+x]
+		}
+	}
+	return samples
+}
+
+// sampledError returns the mean absolute error of samples taken with the same step, placed at (ox, oy).
+func (p lumaPlane) sampledError(samples lumaPlane, ox, oy, step int) float64 {
+	var sum float64
+	for sy := 0; sy < samples.h; sy++ {
+		row := (oy+sy*step)*p.w + ox
+		for sx := 0; sx < samples.w; sx++ {
+			sum += math.Abs(p.pix[row+sx*step] - samples.pix[sy*samples.w+sx])
+		}
+	}
+	return sum / float64(len(samples.pix))
+}
+
+// findSubImage locates inner inside outer using multi-scale template matching.
+// A coarse search over scales and offsets runs on a small copy of outer,
+// and the best candidate is then refined at doubling resolutions up to registrationRefineSize.
+// A last pass at the full size of outer makes the overlap exact to the pixel.
+func findSubImage(outer, inner image.Image) (image.Rectangle, float64) {
+	ob, ib := outer.Bounds(), inner.Bounds()
+	aspect := float64(ib.Dy()) / float64(ib.Dx())
+	longest := float64(max(ob.Dx(), ob.Dy()))
+
+	// All passes but the last resize copies no larger than they need instead of the full images
+	refineK := min(1, registrationRefineSize/longest)
+	refineOuter, refineInner := outer, inner
+	if refineK < 1 {
+		refineOuter = imaging.Resize(outer, max(1, int(float64(ob.Dx())*refineK)), max(1, int(float64(ob.Dy())*refineK)), imaging.Box)
+		if refineWidth := int(float64(ob.Dx()) * refineK); ib.Dx() > 2*refineWidth {
+			refineInner = imaging.Resize(inner, 2*refineWidth, 0, imaging.Box)
+		}
+	}
+
+	k := min(1, registrationWorkSize/longest)
+	work := newLumaPlane(refineOuter, max(1, int(float64(ob.Dx())*k)), max(1, int(float64(ob.Dy())*k)))
+
+	// Largest template width that still fits inside the outer image with the inner aspect ratio
+	maxWidth := min(float64(work.w), float64(work.h)/aspect)
+
+	// Shrink the inner image once so that every template is a cheap resize of a small image
+	coarseInner := refineInner
+	if refineInner.Bounds().Dx() > 2*int(maxWidth) {
+		coarseInner = imaging.Resize(refineInner, 2*int(maxWidth), 0, imaging.Box)
+	}
+
+	bestError := math.Inf(1)
+	var best image.Rectangle
+	for scale := 1.0; scale >= registrationMinScale; scale -= registrationScaleStep {
+		tw := int(math.Round(maxWidth * scale))
+		th := int(math.Round(float64(tw) * aspect))
+		if tw < registrationMinSize || th < registrationMinSize || tw > work.w || th > work.h {
+			continue
+		}
+		template := newLumaPlane(coarseInner, tw, th)
+		// Large templates are sampled sparsely, the refinement passes restore the precision
+		step := max(1, min(tw, th)/(2*registrationMinSize))
+		for oy := 0; oy+th <= work.h; oy++ {
+			for ox := 0; ox+tw <= work.w; ox++ {
+				if e := work.matchError(template, ox, oy, step); e < bestError {
+					bestError = e
+					best = image.Rect(ox, oy, ox+tw, oy+th)
+				}
+			}
+		}
+	}
+	if math.IsInf(bestError, 1) {
+		return image.Rectangle{}, bestError
+	}
+
+	for k < refineK {
+		next := min(refineK, k*2)
+		plane := newLumaPlane(refineOuter, max(1, int(float64(ob.Dx())*next)), max(1, int(float64(ob.Dy())*next)))
+		step := 1
+		if next < 1 {
+			step = 2
+		}
+		candidate := scaleRect(best, next/k)
+		stageInner := refineInner
+		if refineInner.Bounds().Dx() > 2*candidate.Dx() {
+			stageInner = imaging.Resize(refineInner, 2*candidate.Dx(), 0, imaging.Box)
+		}
+		best, bestError = refineMatch(plane, stageInner, aspect, candidate, int(math.Ceil(next/k)), step)
+		k = next
+	}
+	if k < 1 {
+		best, bestError = refineFullSize(outer, inner, aspect, scaleRect(best, 1/k), int(math.Ceil(1/k)))
+	}
+	return best.Add(ob.Min), bestError
+}
+
+// scaleRect multiplies all coordinates of a rectangle by factor.
+func scaleRect(r image.Rectangle, factor float64) image.Rectangle {
+	return image.Rect(
+		int(float64(r.Min.X)*factor),
+		int(float64(r.Min.Y)*factor),
+		int(float64(r.Max.X)*factor),
+		int(float64(r.Max.Y)*factor),
+	)
+}
+
+// refineMatch searches the neighbourhood of a candidate match for the best position and size.
+func refineMatch(plane lumaPlane, inner image.Image, aspect float64, candidate image.Rectangle, radius, step int) (image.Rectangle, float64) {
+	best, bestError := candidate, math.Inf(1)
+	for dw := -radius; dw <= radius; dw++ {
+		tw := candidate.Dx() + dw
+		th := int(math.Round(float64(tw) * aspect))
+		if tw < 1 || th < 1 || tw > plane.w || th > plane.h {
+			continue
+		}
+		template := newLumaPlane(inner, tw, th)
+		rect, e := searchPosition(plane, tw, th, candidate.Min, radius, func(ox, oy int) float64 {
+			return plane.matchError(template, ox, oy, step)
+		})
+		if e < bestError {
+			best, bestError = rect, e
+		}
+	}
+	return best, bestError
+}
+
+// refineFullSize refines a candidate match at the full resolution of outer. Matches within radius of the size of inner
+// are snapped to it, since most crops are not rescaled, and only their position is searched.
+// To stay fast, only the part of outer around the candidate is converted and the templates are sampled every 2*radius pixels.
+func refineFullSize(outer, inner image.Image, aspect float64, candidate image.Rectangle, radius int) (image.Rectangle, float64) {
+	ob, ib := outer.Bounds(), inner.Bounds()
+	var widths []int
+	if absInt(candidate.Dx()-ib.Dx()) <= radius && absInt(candidate.Dy()-ib.Dy()) <= radius {
+		candidate.Max = candidate.Min.Add(ib.Size())
+		widths = []int{ib.Dx()}
+	} else {
+		for dw := -radius; dw <= radius; dw++ {
+			widths = append(widths, candidate.Dx()+dw)
+		}
+	}
+
+	// Leaves room for moving the candidate, and for growing it when its size is searched as well
+	area := candidate.Inset(-2 * radius).Intersect(image.Rect(0, 0, ob.Dx(), ob.Dy()))
+	plane := newLumaPlane(imaging.Crop(outer, area.Add(ob.Min)), area.Dx(), area.Dy())
+	innerPlane := newLumaPlane(inner, ib.Dx(), ib.Dy())
+	step := 2 * radius
+
+	best, bestError := candidate, math.Inf(1)
+	for _, tw := range widths {
+		th := int(math.Round(float64(tw) * aspect))
+		if tw < 1 || th < 1 || tw > plane.w || th > plane.h {
+			continue
+		}
+		samples := innerPlane.sample(tw, th, step)
+		rect, e := searchPosition(plane, tw, th, candidate.Min.Sub(area.Min), radius, func(ox, oy int) float64 {
+			return plane.sampledError(samples, ox, oy, step)
+		})
+		if e < bestError {
+			best, bestError = rect.Add(area.Min), e
+		}
+	}
+	return best, bestError
+}
+
+// searchPosition places a w x h template at every offset within radius of at and returns the position with the lowest error.
+func searchPosition(plane lumaPlane, w, h int, at image.Point, radius int, matchError func(ox, oy int) float64) (image.Rectangle, float64) {
+	var best image.Rectangle
+	bestError := math.Inf(1)
+	for dy := -radius; dy <= radius; dy++ {
+		for dx := -radius; dx <= radius; dx++ {
+			ox, oy := at.X+dx, at.Y+dy
+			if ox < 0 || oy < 0 || ox+w > plane.w || oy+h > plane.h {
+				continue
+			}
+			if e := matchError(ox, oy); e < bestError {
+				bestError = e
+				best = image.Rect(ox, oy, ox+w, oy+h)
+			}
+		}
+	}
+	return best, bestError
+}
+
+// FindOverlap detects whether the smaller of the two images is a (possibly rescaled) crop of the larger one.
+// It reports false if no good match was found or if the match covers practically the whole larger image.
+func FindOverlap(img1, img2 image.Image) (Overlap, bool) {
+	b1, b2 := img1.Bounds(), img2.Bounds()
+
+	// Images that already match as a whole are not crops of each other
+	if thumbnailMAE(img1, img2) < registrationMaxError/2 {
+		return Overlap{}, false
+	}
+
+	outer, inner := img1, img2
+	overlap := Overlap{CroppedImage: 2, Rect2: b2}
+	if b2.Dx()*b2.Dy() > b1.Dx()*b1.Dy() {
+		outer, inner = img2, img1
+		overlap = Overlap{CroppedImage: 1, Rect1: b1}
+	}
+
+	rect, e := findSubImage(outer, inner)
+	if rect.Empty() || e > registrationMaxError {
+		return Overlap{}, false
+	}
+	ob := outer.Bounds()
+	if float64(rect.Dx()*rect.Dy()) > registrationMaxCover*float64(ob.Dx()*ob.Dy()) {
+		return Overlap{}, false
+	}
+
+	if overlap.CroppedImage == 2 {
+		overlap.Rect1 = rect
+	} else {
+		overlap.Rect2 = rect
+	}
+	overlap.Scale = float64(inner.Bounds().Dx()) / float64(rect.Dx())
+	overlap.Error = e
+	return overlap, true
+}
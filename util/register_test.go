@@ -0,0 +1,65 @@
+package util
+
+import (
+	"image"
+	"math/rand"
+	"testing"
+
+	"github.com/disintegration/imaging"
+)
+
+// texturedImage returns a smooth random image, large enough that findSubImage needs its full resolution pass.
+func texturedImage(width, height int) *image.NRGBA {
+	r := rand.New(rand.NewSource(1))
+	noise := image.NewNRGBA(image.Rect(0, 0, 40, 30))
+	for i := range noise.Pix {
+		noise.Pix[i] = uint8(r.Intn(256))
+	}
+	return imaging.Resize(noise, width, height, imaging.Lanczos)
+}
+
+func TestFindOverlapIsExactAtFullResolution(t *testing.T) {
+	img := texturedImage(2000, 1500)
+	rect := image.Rect(263, 151, 1763, 1251)
+
+	overlap, ok := FindOverlap(img, imaging.Crop(img, rect))
+	if !ok {
+		t.Fatal("crop not found")
+	}
+	if overlap.CroppedImage != 2 || overlap.Rect1 != rect || overlap.Rect2 != image.Rect(0, 0, 1500, 1100) {
+		t.Errorf("overlap %+v, want %v cropped from image 1", overlap, rect)
+	}
+}
+
+func TestFindOverlapRescaledCrop(t *testing.T) {
+	img := texturedImage(2000, 1500)
+	rect := image.Rect(263, 151, 1763, 1251)
+
+	overlap, ok := FindOverlap(imaging.Resize(imaging.Crop(img, rect), 750, 550, imaging.Lanczos), img)
+	if !ok {
+		t.Fatal("crop not found")
+	}
+	// Resampling blurs the exact position, a pixel of the smaller image is two of the larger one
+	if overlap.CroppedImage != 1 || !nearRect(overlap.Rect2, rect, 2) {
+		t.Errorf("overlap %+v, want about %v cropped from image 2", overlap, rect)
+	}
+}
+
+func nearRect(a, b image.Rectangle, tolerance int) bool {
+	return absInt(a.Min.X-b.Min.X) <= tolerance && absInt(a.Min.Y-b.Min.Y) <= tolerance &&
+		absInt(a.Max.X-b.Max.X) <= tolerance && absInt(a.Max.Y-b.Max.Y) <= tolerance
+}
+
+func TestDetectTransform(t *testing.T) {
+	img := texturedImage(300, 200)
+	for _, transform := range Transforms {
+		got, aligned := DetectTransform(img, transform.Apply(img))
+		if got != transform {
+			t.Errorf("%v detected as %v", transform, got)
+			continue
+		}
+		if !aligned.Bounds().Eq(img.Bounds()) || lumaMAE(img, aligned) != 0 {
+			t.Errorf("%v: image not transformed back", transform)
+		}
+	}
+}
@@ -0,0 +1,316 @@
+package util
+
+import (
+	"encoding/json"
+	"fmt"
+	"image"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// KeepStrategy decides which image of a pair a rule keeps.
+type KeepStrategy string
+
+const (
+	KeepLargerFile       KeepStrategy = "larger_file"
+	KeepSmallerFile      KeepStrategy = "smaller_file"
+	KeepHigherResolution KeepStrategy = "higher_resolution"
+	KeepNewer            KeepStrategy = "newer"
+	KeepOlder            KeepStrategy = "older"
+	KeepSuggested        KeepStrategy = "suggested"   // The keeper suggested by SuggestKeeper
+	KeepPreferPath       KeepStrategy = "prefer_path" // The image under the rule's path
+)
+
+// RuleConditions must all hold for a rule to apply to a pair. Unset conditions are ignored.
+type RuleConditions struct {
+	MaxMAE         *float64 `json:"max_mae,omitempty"`         // Largest mean absolute error (0-255) between the images
+	SameDimensions bool     `json:"same_dimensions,omitempty"` // Both images have the same width and height
+	SameFormat     bool     `json:"same_format,omitempty"`     // Both images are in the same file format
+	PathPrefix     string   `json:"path_prefix,omitempty"`     // At least one image is under this directory
+}
+
+// Rule resolves pairs matching its conditions by keeping one image and deleting the other.
+type Rule struct {
+	Name string         `json:"name"`
+	When RuleConditions `json:"when"`
+	Keep KeepStrategy   `json:"keep"`
+	Path string         `json:"path,omitempty"` // Directory whose images are kept by prefer_path
+}
+
+// RuleSet is an ordered list of rules, the first rule that applies to a pair resolves it.
+type RuleSet struct {
+	Rules []Rule `json:"rules"`
+}
+
+// Resolution is the decision a rule made for a pair.
+type Resolution struct {
+	Rule        string
+	Keep        CzkawkaImage
+	Delete      CzkawkaImage
+	KeepState   FileState // The version of the kept file the decision was based on
+	DeleteState FileState // The version of the deleted file the decision was based on
+}
+
+// FileState identifies a version of a file by its size and modification time.
+type FileState struct {
+	Size    int64
+	ModTime time.Time
+}
+
+func fileStateOf(info os.FileInfo) FileState {
+	return FileState{Size: info.Size(), ModTime: info.ModTime()}
+}
+
+// Matches reports whether the file at path still has this size and modification time.
+func (s FileState) Matches(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && info.Size() == s.Size && info.ModTime().Equal(s.ModTime)
+}
+
+// Unchanged reports whether both files still exist as they were when the rule resolved the pair.
+func (r Resolution) Unchanged() bool {
+	return r.KeepState.Matches(r.Keep.Path) && r.DeleteState.Matches(r.Delete.Path)
+}
+
+// LoadRuleSet reads and validates a JSON rule file.
+func LoadRuleSet(path string) (*RuleSet, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var rules RuleSet
+	if err := json.Unmarshal(data, &rules); err != nil {
+		return nil, err
+	}
+	for i, rule := range rules.Rules {
+		switch rule.Keep {
+		case KeepLargerFile, KeepSmallerFile, KeepHigherResolution, KeepNewer, KeepOlder, KeepSuggested:
+		case KeepPreferPath:
+			if rule.Path == "" {
+				return nil, fmt.Errorf("rule %d (%s): prefer_path needs a path", i+1, rule.Name)
+			}
+		default:
+			return nil, fmt.Errorf("rule %d (%s): unknown keep strategy %q", i+1, rule.Name, rule.Keep)
+		}
+		if rule.Name == "" {
+			rules.Rules[i].Name = fmt.Sprintf("rule %d", i+1)
+		}
+	}
+	return &rules, nil
+}
+
+// pairFacts computes what the rules need to know about a pair, loading the images only when a rule needs them.
+type pairFacts struct {
+	pair       ImagePair
+	algo       ScalingAlgorithm
+	infos      [2]os.FileInfo
+	images     [2]image.Image
+	mae        *float64
+	suggestion *KeeperSuggestion
+}
+
+func newPairFacts(pair ImagePair, algo ScalingAlgorithm) (*pairFacts, error) {
+	facts := &pairFacts{pair: pair, algo: algo}
+	for i, path := range []string{pair.Image1.Path, pair.Image2.Path} {
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil, err
+		}
+		facts.infos[i] = info
+	}
+	return facts, nil
+}
+
+func (f *pairFacts) loadImages() error {
+	if f.images[0] != nil {
+		return nil
+	}
+	for i, path := range []string{f.pair.Image1.Path, f.pair.Image2.Path} {
+		img, err := LoadImage(path)
+		if err != nil {
+			return err
+		}
+		f.images[i] = img
+	}
+	return nil
+}
+
+func (f *pairFacts) MAE() (float64, error) {
+	if f.mae == nil {
+		if err := f.loadImages(); err != nil {
+			return 0, err
+		}
+		_, stats := ComputeImageDiffFast(&f.images[0], &f.images[1], f.algo, false, DefaultDiffThreshold)
+		f.mae = &stats.MAE
+	}
+	return *f.mae, nil
+}
+
+func (f *pairFacts) Suggestion() (KeeperSuggestion, error) {
+	if f.suggestion == nil {
+		if err := f.loadImages(); err != nil {
+			return KeeperSuggestion{}, err
+		}
+		suggestion := SuggestKeeper(
+			AnalyzeQuality(f.pair.Image1.Path, f.images[0]),
+			AnalyzeQuality(f.pair.Image2.Path, f.images[1]),
+		)
+		f.suggestion = &suggestion
+	}
+	return *f.suggestion, nil
+}
+
+// isUnder reports whether path is inside the directory dir.
+func isUnder(path, dir string) bool {
+	rel, err := filepath.Rel(filepath.Clean(dir), filepath.Clean(path))
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+// matches reports whether all conditions of the rule hold for the pair.
+func (r Rule) matches(f *pairFacts) (bool, error) {
+	image1, image2 := f.pair.Image1, f.pair.Image2
+	if r.When.SameDimensions && (image1.Width != image2.Width || image1.Height != image2.Height) {
+		return false, nil
+	}
+	if r.When.SameFormat && ImageFormat(image1.Path) != ImageFormat(image2.Path) {
+		return false, nil
+	}
+	if r.When.PathPrefix != "" && !isUnder(image1.Path, r.When.PathPrefix) && !isUnder(image2.Path, r.When.PathPrefix) {
+		return false, nil
+	}
+	if r.When.MaxMAE != nil {
+		mae, err := f.MAE()
+		if err != nil {
+			return false, err
+		}
+		if mae > *r.When.MaxMAE {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// keeper returns which image (1 or 2) the rule keeps, or 0 if its strategy cannot tell them apart.
+func (r Rule) keeper(f *pairFacts) (int, error) {
+	image1, image2 := f.pair.Image1, f.pair.Image2
+	prefer := func(first, second bool) int {
+		switch {
+		case first && !second:
+			return 1
+		case second && !first:
+			return 2
+		}
+		return 0
+	}
+
+	size1, size2 := f.infos[0].Size(), f.infos[1].Size()
+	time1, time2 := f.infos[0].ModTime(), f.infos[1].ModTime()
+	pixels1, pixels2 := image1.Width*image1.Height, image2.Width*image2.Height
+	switch r.Keep {
+	case KeepLargerFile:
+		return prefer(size1 > size2, size2 > size1), nil
+	case KeepSmallerFile:
+		return prefer(size1 < size2, size2 < size1), nil
+	case KeepHigherResolution:
+		return prefer(pixels1 > pixels2, pixels2 > pixels1), nil
+	case KeepNewer:
+		return prefer(time1.After(time2), time2.After(time1)), nil
+	case KeepOlder:
+		return prefer(time1.Before(time2), time2.Before(time1)), nil
+	case KeepPreferPath:
+		return prefer(isUnder(image1.Path, r.Path), isUnder(image2.Path, r.Path)), nil
+	case KeepSuggested:
+		suggestion, err := f.Suggestion()
+		return suggestion.Keep, err
+	}
+	return 0, nil
+}
+
+// Resolve finds the first rule that applies to the pair and picks a keeper.
+// It reports false if no rule resolves the pair.
+func (rs *RuleSet) Resolve(pair ImagePair, algo ScalingAlgorithm) (Resolution, bool, error) {
+	facts, err := newPairFacts(pair, algo)
+	if err != nil {
+		return Resolution{}, false, err
+	}
+	for _, rule := range rs.Rules {
+		ok, err := rule.matches(facts)
+		if err != nil {
+			return Resolution{}, false, err
+		}
+		if !ok {
+			continue
+		}
+		keep, err := rule.keeper(facts)
+		if err != nil {
+			return Resolution{}, false, err
+		}
+		state1, state2 := fileStateOf(facts.infos[0]), fileStateOf(facts.infos[1])
+		switch keep {
+		case 1:
+			return Resolution{Rule: rule.Name, Keep: pair.Image1, Delete: pair.Image2, KeepState: state1, DeleteState: state2}, true, nil
+		case 2:
+			return Resolution{Rule: rule.Name, Keep: pair.Image2, Delete: pair.Image1, KeepState: state2, DeleteState: state1}, true, nil
+		}
+	}
+	return Resolution{}, false, nil
+}
+
+// ResolveQueue runs the rules over the whole queue without deleting anything.
+// Every deleted file keeps a surviving keeper: pairs involving a file that an earlier resolution deletes are skipped,
+// and resolutions that would delete an earlier keeper are left for manual review.
+// Pairs that fail to load are reported as errors and left unresolved.
+func (rs *RuleSet) ResolveQueue(queue []ImagePair, algo ScalingAlgorithm, progress func(done, total int)) ([]Resolution, []error) {
+	var resolutions []Resolution
+	var errs []error
+	deleted := make(map[string]bool)
+	kept := make(map[string]bool)
+	for i, pair := range queue {
+		if progress != nil {
+			progress(i, len(queue))
+		}
+		if deleted[pair.Image1.Path] || deleted[pair.Image2.Path] {
+			continue
+		}
+		resolution, ok, err := rs.Resolve(pair, algo)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s and %s: %w", pair.Image1.Path, pair.Image2.Path, err))
+			continue
+		}
+		if !ok || kept[resolution.Delete.Path] {
+			continue
+		}
+		resolutions = append(resolutions, resolution)
+		deleted[resolution.Delete.Path] = true
+		kept[resolution.Keep.Path] = true
+	}
+	return resolutions, errs
+}
+
+// ResolutionReport describes what applying the resolutions would delete, grouped by rule.
+func ResolutionReport(resolutions []Resolution, queueLength int) string {
+	var report strings.Builder
+	var freed int64
+	perRule := make(map[string]int)
+	var ruleOrder []string
+	for _, resolution := range resolutions {
+		if perRule[resolution.Rule] == 0 {
+			ruleOrder = append(ruleOrder, resolution.Rule)
+		}
+		perRule[resolution.Rule]++
+		freed += resolution.Delete.Size
+	}
+
+	fmt.Fprintf(&report, "%d of %d pairs resolved, %d files (%s bytes) would be deleted\n",
+		len(resolutions), queueLength, len(resolutions), FormatIntWithSpaces(freed))
+	for _, rule := range ruleOrder {
+		fmt.Fprintf(&report, "  %s: %d pairs\n", rule, perRule[rule])
+	}
+	report.WriteString("\n")
+	for _, resolution := range resolutions {
+		fmt.Fprintf(&report, "[%s] delete %s\n    keep %s\n", resolution.Rule, resolution.Delete.Path, resolution.Keep.Path)
+	}
+	return report.String()
+}
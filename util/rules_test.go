@@ -0,0 +1,92 @@
+package util
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func writeSizedFile(t *testing.T, dir, name string, size int) CzkawkaImage {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, []byte(strings.Repeat("x", size)), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return CzkawkaImage{Path: path, Size: int64(size), Width: 10, Height: 10}
+}
+
+func TestResolveQueueNeverDeletesAKeeper(t *testing.T) {
+	dir := t.TempDir()
+	a := writeSizedFile(t, dir, "a.png", 20)
+	b := writeSizedFile(t, dir, "b.png", 10)
+	c := writeSizedFile(t, dir, "c.png", 30)
+
+	rules := &RuleSet{Rules: []Rule{{Name: "larger", Keep: KeepLargerFile}}}
+	queue := []ImagePair{{Image1: a, Image2: b}, {Image1: a, Image2: c}}
+	resolutions, errs := rules.ResolveQueue(queue, Bilinear, nil)
+	if len(errs) > 0 {
+		t.Fatal(errs)
+	}
+
+	if len(resolutions) != 1 {
+		t.Fatalf("got %d resolutions, want 1: %+v", len(resolutions), resolutions)
+	}
+	if resolutions[0].Delete.Path != b.Path || resolutions[0].Keep.Path != a.Path {
+		t.Errorf("got delete %s keep %s, want delete %s keep %s",
+			resolutions[0].Delete.Path, resolutions[0].Keep.Path, b.Path, a.Path)
+	}
+}
+
+func TestResolveQueueSkipsPairsWithDeletedFiles(t *testing.T) {
+	dir := t.TempDir()
+	a := writeSizedFile(t, dir, "a.png", 20)
+	b := writeSizedFile(t, dir, "b.png", 10)
+	c := writeSizedFile(t, dir, "c.png", 5)
+
+	rules := &RuleSet{Rules: []Rule{{Name: "larger", Keep: KeepLargerFile}}}
+	queue := []ImagePair{{Image1: a, Image2: b}, {Image1: b, Image2: c}, {Image1: a, Image2: c}}
+	resolutions, errs := rules.ResolveQueue(queue, Bilinear, nil)
+	if len(errs) > 0 {
+		t.Fatal(errs)
+	}
+
+	var got []string
+	for _, resolution := range resolutions {
+		got = append(got, filepath.Base(resolution.Delete.Path)+" for "+filepath.Base(resolution.Keep.Path))
+	}
+	want := "b.png for a.png, c.png for a.png"
+	if strings.Join(got, ", ") != want {
+		t.Errorf("got %q, want %q", strings.Join(got, ", "), want)
+	}
+}
+
+func TestResolutionUnchanged(t *testing.T) {
+	dir := t.TempDir()
+	a := writeSizedFile(t, dir, "a.png", 20)
+	b := writeSizedFile(t, dir, "b.png", 10)
+
+	rules := &RuleSet{Rules: []Rule{{Name: "larger", Keep: KeepLargerFile}}}
+	resolution, ok, err := rules.Resolve(ImagePair{Image1: a, Image2: b}, Bilinear)
+	if err != nil || !ok {
+		t.Fatalf("pair not resolved: %v", err)
+	}
+	if !resolution.Unchanged() {
+		t.Fatal("fresh resolution reported as changed")
+	}
+
+	// Same size, different content and modification time
+	writeSizedFile(t, dir, "b.png", 10)
+	if err := os.Chtimes(b.Path, time.Now(), time.Now().Add(time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	if resolution.Unchanged() {
+		t.Error("resolution of a modified file reported as unchanged")
+	}
+
+	os.Remove(b.Path)
+	if resolution.Unchanged() {
+		t.Error("resolution of a removed file reported as unchanged")
+	}
+}
@@ -0,0 +1,246 @@
+package util
+
+import (
+	"image"
+	"image/color"
+	"math"
+
+	"github.com/disintegration/imaging"
+)
+
+const (
+	ssimWindowRadius = 5   // Gaussian window of 11x11 pixels
+	ssimWindowSigma  = 1.5 // Standard deviation of the Gaussian window
+)
+
+var (
+	ssimC1 = math.Pow(0.01*255, 2)
+	ssimC2 = math.Pow(0.03*255, 2)
+
+	// Per-scale weights from Wang, Simoncelli and Bovik, "Multi-scale structural similarity for image quality assessment".
+	msssimWeights = []float64{0.0448, 0.2856, 0.3001, 0.2363, 0.1333}
+)
+
+// SSIMResult holds the outcome of a structural similarity comparison.
+type SSIMResult struct {
+	Score  float64   // Mean SSIM over the whole image, 1 means identical
+	Map    []float64 // Per-pixel SSIM values in row-major order
+	Width  int
+	Height int
+}
+
+// MatchBounds resizes img2 to the bounds of img1 if they differ,
+// so that both images can be compared pixel by pixel.
+func MatchBounds(img1, img2 image.Image, algo ScalingAlgorithm) image.Image {
+	bounds := img1.Bounds()
+	if img2.Bounds().Eq(bounds) {
+		return img2
+	}
+	var filter imaging.ResampleFilter
+	switch algo {
+	case NearestNeighbor:
+		filter = imaging.NearestNeighbor
+	default:
+		filter = imaging.Linear
+	}
+	return imaging.Resize(img2, bounds.Dx(), bounds.Dy(), filter)
+}
+
+// luminance converts an image into a row-major slice of luma values in the 0-255 range.
+func luminance(img image.Image) []float64 {
+	bounds := img.Bounds()
+	w, h := bounds.Dx(), bounds.Dy()
+	lum := make([]float64, w*h)
+	read := newRowReader(img)
+	row := make([]uint32, w*4)
+	for y := 0; y < h; y++ {
+		read(y, row)
+		for x := 0; x < w; x++ {
+			r, g, b := row[x*4], row[x*4+1], row[x*4+2]
+			lum[y*w+x] = (0.299*float64(r) + 0.587*float64(g) + 0.114*float64(b)) / 257
+		}
+	}
+	return lum
+}
+
+// gaussianKernel returns a normalized 1D Gaussian kernel used for the SSIM window.
+func gaussianKernel() []float64 {
+	kernel := make([]float64, 2*ssimWindowRadius+1)
+	var sum float64
+	for i := range kernel {
+		d := float64(i - ssimWindowRadius)
+		kernel[i] = math.Exp(-(d * d) / (2 * ssimWindowSigma * ssimWindowSigma))
+		sum += kernel[i]
+	}
+	for i := range kernel {
+		kernel[i] /= sum
+	}
+	return kernel
+}
+
+// gaussianBlur applies a separable Gaussian filter to src, clamping samples at the edges.
+func gaussianBlur(src []float64, w, h int, kernel []float64) []float64 {
+	tmp := make([]float64, w*h)
+	dst := make([]float64, w*h)
+	r := len(kernel) / 2
+
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			var sum float64
+			for k := -r; k <= r; k++ {
+				sx := min(max(x+k, 0), w-1)
+				sum += src[y*w+sx] * kernel[k+r]
+			}
+			tmp[y*w+x] = sum
+		}
+	}
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			var sum float64
+			for k := -r; k <= r; k++ {
+				sy := min(max(y+k, 0), h-1)
+				sum += tmp[sy*w+x] * kernel[k+r]
+			}
+			dst[y*w+x] = sum
+		}
+	}
+	return dst
+}
+
+// ssimMaps computes the per-pixel SSIM map and the contrast-structure map of two luma planes.
+func ssimMaps(x, y []float64, w, h int) (ssim []float64, cs []float64) {
+	kernel := gaussianKernel()
+	n := w * h
+
+	xx := make([]float64, n)
+	yy := make([]float64, n)
+	xy := make([]float64, n)
+	for i := 0; i < n; i++ {
+		xx[i] = x[i] * x[i]
+		yy[i] = y[i] * y[i]
+		xy[i] = x[i] * y[i]
+	}
+
+	muX := gaussianBlur(x, w, h, kernel)
+	muY := gaussianBlur(y, w, h, kernel)
+	sigmaXX := gaussianBlur(xx, w, h, kernel)
+	sigmaYY := gaussianBlur(yy, w, h, kernel)
+	sigmaXY := gaussianBlur(xy, w, h, kernel)
+
+	ssim = make([]float64, n)
+	cs = make([]float64, n)
+	for i := 0; i < n; i++ {
+		mx, my := muX[i], muY[i]
+		vx := sigmaXX[i] - mx*mx
+		vy := sigmaYY[i] - my*my
+		cov := sigmaXY[i] - mx*my
+
+		cs[i] = (2*cov + ssimC2) / (vx + vy + ssimC2)
+		ssim[i] = (2*mx*my + ssimC1) / (mx*mx + my*my + ssimC1) * cs[i]
+	}
+	return ssim, cs
+}
+
+// mean returns the arithmetic mean of the values.
+func mean(values []float64) float64 {
+	if len(values) == 0 {
+		return 0
+	}
+	var sum float64
+	for _, v := range values {
+		sum += v
+	}
+	return sum / float64(len(values))
+}
+
+// downsample halves the resolution of a plane by averaging 2x2 blocks.
+func downsample(src []float64, w, h int) ([]float64, int, int) {
+	nw, nh := w/2, h/2
+	dst := make([]float64, nw*nh)
+	for y := 0; y < nh; y++ {
+		for x := 0; x < nw; x++ {
+			i := 2*y*w + 2*x
+			dst[y*nw+x] = (src[i] + src[i+1] + src[i+w] + src[i+w+1]) / 4
+		}
+	}
+	return dst, nw, nh
+}
+
+// ComputeSSIM computes the structural similarity between two images on their luminance.
+// If the images have different bounds, img2 is resized to match img1 first.
+func ComputeSSIM(img1, img2 *image.Image, algo ScalingAlgorithm) SSIMResult {
+	bounds := (*img1).Bounds()
+	w, h := bounds.Dx(), bounds.Dy()
+
+	x := luminance(*img1)
+	y := luminance(MatchBounds(*img1, *img2, algo))
+	ssim, _ := ssimMaps(x, y, w, h)
+
+	return SSIMResult{
+		Score:  mean(ssim),
+		Map:    ssim,
+		Width:  w,
+		Height: h,
+	}
+}
+
+// ComputeMSSSIM computes the multi-scale structural similarity between two images.
+// Scales that would shrink the image below the SSIM window are skipped
+// and the remaining weights are renormalized.
+func ComputeMSSSIM(img1, img2 *image.Image, algo ScalingAlgorithm) float64 {
+	bounds := (*img1).Bounds()
+	w, h := bounds.Dx(), bounds.Dy()
+
+	x := luminance(*img1)
+	y := luminance(MatchBounds(*img1, *img2, algo))
+
+	windowSize := 2*ssimWindowRadius + 1
+	scales := 0
+	for sw, sh := w, h; scales < len(msssimWeights) && sw >= windowSize && sh >= windowSize; scales++ {
+		sw, sh = sw/2, sh/2
+	}
+	if scales == 0 {
+		// Image is too small for a windowed comparison, fall back to single-scale SSIM
+		ssim, _ := ssimMaps(x, y, w, h)
+		return mean(ssim)
+	}
+
+	var weightSum float64
+	for _, weight := range msssimWeights[:scales] {
+		weightSum += weight
+	}
+
+	result := 1.0
+	for i := 0; i < scales; i++ {
+		ssim, cs := ssimMaps(x, y, w, h)
+		weight := msssimWeights[i] / weightSum
+
+		value := mean(cs)
+		if i == scales-1 {
+			value = mean(ssim)
+		}
+		// Negative values would turn fractional powers into NaN
+		result *= math.Pow(max(value, 0), weight)
+
+		if i < scales-1 {
+			x, _, _ = downsample(x, w, h)
+			y, w, h = downsample(y, w, h)
+		}
+	}
+	return result
+}
+
+// SSIMMapImage renders an SSIM map as an image where
+// structurally identical areas are black and dissimilar areas are bright.
+func SSIMMapImage(result SSIMResult) image.Image {
+	img := image.NewRGBA(image.Rect(0, 0, result.Width, result.Height))
+	for y := 0; y < result.Height; y++ {
+		for x := 0; x < result.Width; x++ {
+			// SSIM ranges from -1 to 1, map 1 to black and anything at or below 0 to full brightness
+			dissimilarity := math.Min(math.Max(1-result.Map[y*result.Width+x], 0), 1)
+			v := uint8(dissimilarity * 255)
+			img.Set(x, y, color.RGBA{R: v, G: v, B: v, A: 255})
+		}
+	}
+	return img
+}
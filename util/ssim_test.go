@@ -0,0 +1,92 @@
+package util
+
+import (
+	"image"
+	"image/color"
+	"math"
+	"testing"
+
+	"github.com/disintegration/imaging"
+)
+
+func TestSSIMOfIdenticalImagesIsOne(t *testing.T) {
+	var img image.Image = texturedImage(160, 120)
+	same := image.Image(imaging.Clone(img))
+
+	if score := ComputeSSIM(&img, &same, Bilinear).Score; math.Abs(score-1) > 1e-9 {
+		t.Errorf("SSIM %v, want 1", score)
+	}
+	if score := ComputeMSSSIM(&img, &same, Bilinear); math.Abs(score-1) > 1e-9 {
+		t.Errorf("MS-SSIM %v, want 1", score)
+	}
+}
+
+func TestSSIMDecreasesWithDegradation(t *testing.T) {
+	var img image.Image = texturedImage(160, 120)
+
+	previousSSIM, previousMSSSIM := 1.0, 1.0
+	for _, sigma := range []float64{0.5, 1, 2, 4} {
+		var blurred image.Image = imaging.Blur(img, sigma)
+		ssim := ComputeSSIM(&img, &blurred, Bilinear).Score
+		msssim := ComputeMSSSIM(&img, &blurred, Bilinear)
+		if ssim >= previousSSIM {
+			t.Errorf("blur sigma %v: SSIM %v, want below %v", sigma, ssim, previousSSIM)
+		}
+		if msssim >= previousMSSSIM {
+			t.Errorf("blur sigma %v: MS-SSIM %v, want below %v", sigma, msssim, previousMSSSIM)
+		}
+		previousSSIM, previousMSSSIM = ssim, msssim
+	}
+}
+
+func TestSSIMHandlesImagesSmallerThanTheWindow(t *testing.T) {
+	for _, size := range []image.Point{{1, 1}, {5, 5}, {10, 3}} {
+		var img image.Image = texturedImage(size.X, size.Y)
+		var inverted image.Image = imaging.Invert(img)
+
+		result := ComputeSSIM(&img, &img, Bilinear)
+		if math.Abs(result.Score-1) > 1e-9 || len(result.Map) != size.X*size.Y {
+			t.Errorf("%v: SSIM %v with %d map values", size, result.Score, len(result.Map))
+		}
+		if score := ComputeMSSSIM(&img, &img, Bilinear); math.Abs(score-1) > 1e-9 {
+			t.Errorf("%v: MS-SSIM %v, want 1", size, score)
+		}
+		if score := ComputeMSSSIM(&img, &inverted, Bilinear); math.IsNaN(score) || score >= 1 {
+			t.Errorf("%v: MS-SSIM of the inverted image %v", size, score)
+		}
+	}
+}
+
+func TestMSSSIMRenormalizesWeightsOfSkippedScales(t *testing.T) {
+	// 24 pixels only leave room for two scales, 24 and 12, before dropping below the 11 pixel window
+	var img image.Image = texturedImage(24, 24)
+	var blurred image.Image = imaging.Blur(img, 1)
+
+	x, y := luminance(img), luminance(blurred)
+	_, cs := ssimMaps(x, y, 24, 24)
+	x, _, _ = downsample(x, 24, 24)
+	y, w, h := downsample(y, 24, 24)
+	ssim, _ := ssimMaps(x, y, w, h)
+	weightSum := msssimWeights[0] + msssimWeights[1]
+	want := math.Pow(mean(cs), msssimWeights[0]/weightSum) * math.Pow(mean(ssim), msssimWeights[1]/weightSum)
+
+	if got := ComputeMSSSIM(&img, &blurred, Bilinear); math.Abs(got-want) > 1e-9 {
+		t.Errorf("MS-SSIM %v, want %v", got, want)
+	}
+}
+
+func TestSSIMMapImage(t *testing.T) {
+	result := SSIMResult{Map: []float64{1, 0.5, 0, -1}, Width: 2, Height: 2}
+	img := SSIMMapImage(result)
+
+	if img.Bounds() != image.Rect(0, 0, 2, 2) {
+		t.Fatalf("bounds %v", img.Bounds())
+	}
+	want := []uint8{0, 127, 255, 255}
+	for i, v := range want {
+		got := color.RGBAModel.Convert(img.At(i%2, i/2)).(color.RGBA)
+		if got != (color.RGBA{R: v, G: v, B: v, A: 255}) {
+			t.Errorf("pixel %d = %v, want gray %d", i, got, v)
+		}
+	}
+}
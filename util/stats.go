@@ -0,0 +1,99 @@
+package util
+
+import "math"
+
+// DefaultDiffThreshold is the per-channel difference (0-255) above which a pixel counts as differing.
+const DefaultDiffThreshold = 8
+
+// DiffStats holds the error statistics gathered while computing an image difference.
+// All errors are expressed on the 0-255 scale.
+type DiffStats struct {
+	PixelCount uint64     // Number of compared pixels
+	MAE        float64    // Mean absolute error over the R, G and B channels
+	ChannelMAE [3]float64 // Mean absolute error of the R, G and B channels separately
+	AlphaMAE   float64    // Mean absolute error of the alpha channel
+	RMSE       float64    // Root mean squared error over the R, G and B channels
+	PSNR       float64    // Peak signal-to-noise ratio in dB, +Inf for identical images
+	MaxError   uint32     // Largest single-channel difference, including alpha
+
+	Threshold       uint32 // Threshold used for DifferingPixels
+	DifferingPixels uint64 // Pixels where any channel differs by more than Threshold
+}
+
+// Identical reports whether no channel of any pixel differs.
+func (s DiffStats) Identical() bool {
+	return s.MaxError == 0
+}
+
+// DifferingPercent returns the share of pixels over the threshold as a percentage.
+func (s DiffStats) DifferingPercent() float64 {
+	if s.PixelCount == 0 {
+		return 0
+	}
+	return float64(s.DifferingPixels) / float64(s.PixelCount) * 100
+}
+
+// diffAccumulator collects per-pixel channel differences into DiffStats.
+type diffAccumulator struct {
+	threshold  uint32
+	sum        [4]uint64
+	sumSquares uint64
+	maxError   uint32
+	differing  uint64
+	count      uint64
+}
+
+// add records the absolute channel differences of a single pixel.
+func (a *diffAccumulator) add(dr, dg, db, da uint32) {
+	a.sum[0] += uint64(dr)
+	a.sum[1] += uint64(dg)
+	a.sum[2] += uint64(db)
+	a.sum[3] += uint64(da)
+	a.sumSquares += uint64(dr*dr) + uint64(dg*dg) + uint64(db*db)
+
+	m := max(dr, dg, db, da)
+	a.maxError = max(a.maxError, m)
+	if m > a.threshold {
+		a.differing++
+	}
+	a.count++
+}
+
+// merge adds the differences recorded by another accumulator with the same threshold.
+func (a *diffAccumulator) merge(other *diffAccumulator) {
+	for i := range a.sum {
+		a.sum[i] += other.sum[i]
+	}
+	a.sumSquares += other.sumSquares
+	a.maxError = max(a.maxError, other.maxError)
+	a.differing += other.differing
+	a.count += other.count
+}
+
+// stats converts the accumulated sums into DiffStats.
+func (a *diffAccumulator) stats() DiffStats {
+	s := DiffStats{
+		PixelCount:      a.count,
+		MaxError:        a.maxError,
+		Threshold:       a.threshold,
+		DifferingPixels: a.differing,
+		PSNR:            math.Inf(1),
+	}
+	if a.count == 0 {
+		return s
+	}
+
+	n := float64(a.count)
+	for i := range s.ChannelMAE {
+		s.ChannelMAE[i] = float64(a.sum[i]) / n
+	}
+	s.AlphaMAE = float64(a.sum[3]) / n
+	s.MAE = float64(a.sum[0]+a.sum[1]+a.sum[2]) / (n * 3)
+
+	mse := float64(a.sumSquares) / (n * 3)
+	s.RMSE = math.Sqrt(mse)
+	if mse > 0 {
+		s.PSNR = 10 * math.Log10(255*255/mse)
+	}
+	return s
+}
@@ -0,0 +1,160 @@
+package util
+
+import (
+	"image"
+
+	"github.com/disintegration/imaging"
+)
+
+// Transform is one of the eight dihedral transforms of an image.
+type Transform int
+
+const (
+	// Identity leaves the image unchanged.
+	Identity Transform = iota
+	// Rotate90 rotates the image 90° clockwise.
+	Rotate90
+	// Rotate180 rotates the image 180°.
+	Rotate180
+	// Rotate270 rotates the image 270° clockwise.
+	Rotate270
+	// FlipHorizontal mirrors the image left to right.
+	FlipHorizontal
+	// FlipVertical mirrors the image top to bottom.
+	FlipVertical
+	// Transpose mirrors the image along its top-left to bottom-right diagonal.
+	Transpose
+	// Transverse mirrors the image along its top-right to bottom-left diagonal.
+	Transverse
+)
+
+// Transforms lists all dihedral transforms.
+var Transforms = []Transform{Identity, Rotate90, Rotate180, Rotate270, FlipHorizontal, FlipVertical, Transpose, Transverse}
+
+// Size of the thumbnails the transforms are compared on.
+const transformThumbnailSize = 64
+
+// A transform other than Identity is only reported if its error is below this share of the Identity error,
+// which keeps symmetric images from being reported as mirrored.
+const transformMinImprovement = 0.9
+
+func (t Transform) String() string {
+	switch t {
+	case Identity:
+		return "unchanged"
+	case Rotate90:
+		return "rotated 90° clockwise"
+	case Rotate180:
+		return "rotated 180°"
+	case Rotate270:
+		return "rotated 270° clockwise"
+	case FlipHorizontal:
+		return "mirrored horizontally"
+	case FlipVertical:
+		return "mirrored vertically"
+	case Transpose:
+		return "transposed"
+	case Transverse:
+		return "transversed"
+	}
+	return "unknown"
+}
+
+// Inverse returns the transform undoing t.
+func (t Transform) Inverse() Transform {
+	switch t {
+	case Rotate90:
+		return Rotate270
+	case Rotate270:
+		return Rotate90
+	}
+	return t
+}
+
+// Apply returns the transformed image.
+func (t Transform) Apply(img image.Image) image.Image {
+	switch t {
+	case Rotate90:
+		return imaging.Rotate270(img) // imaging rotates counter-clockwise
+	case Rotate180:
+		return imaging.Rotate180(img)
+	case Rotate270:
+		return imaging.Rotate90(img)
+	case FlipHorizontal:
+		return imaging.FlipH(img)
+	case FlipVertical:
+		return imaging.FlipV(img)
+	case Transpose:
+		return imaging.Transpose(img)
+	case Transverse:
+		return imaging.Transverse(img)
+	}
+	return img
+}
+
+// MapFraction returns where a position, as fractions of the width and height of an image,
+// ends up in the transformed image.
+func (t Transform) MapFraction(x, y float64) (float64, float64) {
+	switch t {
+	case Rotate90:
+		return 1 - y, x
+	case Rotate180:
+		return 1 - x, 1 - y
+	case Rotate270:
+		return y, 1 - x
+	case FlipHorizontal:
+		return 1 - x, y
+	case FlipVertical:
+		return x, 1 - y
+	case Transpose:
+		return y, x
+	case Transverse:
+		return 1 - y, 1 - x
+	}
+	return x, y
+}
+
+// transformThumbnail shrinks an image to the square thumbnail transforms are compared on.
+// Since the thumbnail is square, transforming it gives the thumbnail of the transformed image.
+func transformThumbnail(img image.Image) *image.NRGBA {
+	return imaging.Resize(img, transformThumbnailSize, transformThumbnailSize, imaging.Box)
+}
+
+// lumaMAE returns the mean absolute luminance error of two images of the same size.
+func lumaMAE(img1, img2 image.Image) float64 {
+	luma1, luma2 := luminance(img1), luminance(img2)
+	var sum float64
+	for i := range luma1 {
+		d := luma1[i] - luma2[i]
+		if d < 0 {
+			d = -d
+		}
+		sum += d
+	}
+	return sum / float64(len(luma1))
+}
+
+// thumbnailMAE compares two images on small thumbnails and returns their mean absolute error.
+func thumbnailMAE(img1, img2 image.Image) float64 {
+	return lumaMAE(transformThumbnail(img1), transformThumbnail(img2))
+}
+
+// DetectTransform finds the dihedral transform of img2 that best matches img1.
+// It returns how img2 relates to img1, i.e. img2 is img1 with the returned transform applied,
+// along with img2 transformed back to the orientation of img1.
+// The transforms are tried on thumbnails, only the best one is applied to img2 itself.
+func DetectTransform(img1, img2 image.Image) (Transform, image.Image) {
+	thumb1, thumb2 := transformThumbnail(img1), transformThumbnail(img2)
+	identityError := lumaMAE(thumb1, thumb2)
+	best, bestError := Identity, identityError
+	for _, t := range Transforms[1:] {
+		if e := lumaMAE(thumb1, t.Apply(thumb2)); e < bestError {
+			best, bestError = t, e
+		}
+	}
+
+	if best == Identity || bestError >= identityError*transformMinImprovement {
+		return Identity, img2
+	}
+	return best.Inverse(), best.Apply(img2)
+}
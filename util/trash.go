@@ -0,0 +1,203 @@
+package util
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Trash moves files to the freedesktop.org trash.
+// Files on the same device as the home trash go there, files on other devices go to the trash
+// at the top directory of their volume, $topdir/.Trash/$uid or $topdir/.Trash-$uid.
+type Trash struct {
+	homeTrash string
+	uid       string
+}
+
+// TrashedFile is a file that has been moved to the trash.
+type TrashedFile struct {
+	OriginalPath string
+	FilesPath    string // Location of the file inside the trash
+	InfoPath     string // Location of its .trashinfo file
+}
+
+// NewTrash creates a trash using the given directory as the home trash.
+func NewTrash(homeTrash string) *Trash {
+	return &Trash{homeTrash: homeTrash, uid: strconv.Itoa(os.Getuid())}
+}
+
+// HomeTrash returns the trash of the current user, Trash under $XDG_DATA_HOME or ~/.local/share.
+func HomeTrash() (*Trash, error) {
+	dir, err := dataHome()
+	if err != nil {
+		return nil, err
+	}
+	return NewTrash(filepath.Join(dir, "Trash")), nil
+}
+
+// MoveToTrash moves the file to the trash directory on its device and writes its .trashinfo file.
+// If the volume has no usable trash, the file is copied to the home trash instead.
+func (t *Trash) MoveToTrash(path string) (TrashedFile, error) {
+	path, err := filepath.Abs(path)
+	if err != nil {
+		return TrashedFile{}, err
+	}
+	if _, err := os.Lstat(path); err != nil {
+		return TrashedFile{}, err
+	}
+
+	fileDevice, err := deviceID(filepath.Dir(path))
+	if err != nil {
+		return TrashedFile{}, err
+	}
+	if err := os.MkdirAll(t.homeTrash, 0700); err != nil {
+		return TrashedFile{}, err
+	}
+	homeDevice, err := deviceID(t.homeTrash)
+	if err != nil {
+		return TrashedFile{}, err
+	}
+
+	if fileDevice != homeDevice {
+		topDir, err := volumeTopDir(filepath.Dir(path), fileDevice)
+		if err == nil {
+			if trashDir, err := t.volumeTrash(topDir); err == nil {
+				// Paths in volume trashes are relative to the top directory, so the volume can be mounted elsewhere
+				relative, err := filepath.Rel(topDir, path)
+				if err == nil {
+					if trashed, err := moveToTrashDir(trashDir, path, relative); err == nil {
+						return trashed, nil
+					}
+				}
+			}
+		}
+	}
+	return moveToTrashDir(t.homeTrash, path, path)
+}
+
+// volumeTrash returns the trash directory of the current user at the top directory of a volume.
+// The shared $topdir/.Trash is only used if it is a real directory with the sticky bit set,
+// otherwise $topdir/.Trash-$uid is used, creating it if needed.
+func (t *Trash) volumeTrash(topDir string) (string, error) {
+	shared := filepath.Join(topDir, ".Trash")
+	if info, err := os.Lstat(shared); err == nil && info.IsDir() && info.Mode()&os.ModeSticky != 0 {
+		dir := filepath.Join(shared, t.uid)
+		if err := os.MkdirAll(dir, 0700); err == nil {
+			return dir, nil
+		}
+	}
+
+	dir := filepath.Join(topDir, ".Trash-"+t.uid)
+	if err := os.Mkdir(dir, 0700); err != nil && !errors.Is(err, os.ErrExist) {
+		return "", err
+	}
+	if info, err := os.Lstat(dir); err != nil || !info.IsDir() {
+		return "", fmt.Errorf("%s is not a directory", dir)
+	}
+	return dir, nil
+}
+
+// volumeTopDir returns the highest ancestor of dir that is still on the given device, i.e. its mount point.
+func volumeTopDir(dir string, device uint64) (string, error) {
+	for {
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return dir, nil
+		}
+		parentDevice, err := deviceID(parent)
+		if err != nil {
+			return "", err
+		}
+		if parentDevice != device {
+			return dir, nil
+		}
+		dir = parent
+	}
+}
+
+// moveToTrashDir moves the file into the files directory of a trash and writes its .trashinfo file,
+// recording infoPath as the original location.
+// Name collisions are resolved by appending a number to the name, the info file is created exclusively
+// so that concurrent trashing cannot claim the same name.
+func moveToTrashDir(trashDir, path, infoPath string) (TrashedFile, error) {
+	filesDir := filepath.Join(trashDir, "files")
+	infoDir := filepath.Join(trashDir, "info")
+	for _, dir := range []string{filesDir, infoDir} {
+		if err := os.MkdirAll(dir, 0700); err != nil {
+			return TrashedFile{}, err
+		}
+	}
+
+	info := fmt.Sprintf("[Trash Info]\nPath=%s\nDeletionDate=%s\n",
+		(&url.URL{Path: infoPath}).EscapedPath(),
+		time.Now().Format("2006-01-02T15:04:05"),
+	)
+
+	base := filepath.Base(path)
+	ext := filepath.Ext(base)
+	stem := strings.TrimSuffix(base, ext)
+	for n := 1; ; n++ {
+		name := base
+		if n > 1 {
+			name = fmt.Sprintf("%s.%d%s", stem, n, ext)
+		}
+		trashed := TrashedFile{
+			OriginalPath: path,
+			FilesPath:    filepath.Join(filesDir, name),
+			InfoPath:     filepath.Join(infoDir, name+".trashinfo"),
+		}
+
+		file, err := os.OpenFile(trashed.InfoPath, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+		if errors.Is(err, os.ErrExist) {
+			continue
+		}
+		if err != nil {
+			return TrashedFile{}, err
+		}
+		// A file left in files without its info file still blocks the name
+		if _, err := os.Lstat(trashed.FilesPath); err == nil {
+			file.Close()
+			os.Remove(trashed.InfoPath)
+			continue
+		}
+
+		_, err = file.WriteString(info)
+		if closeErr := file.Close(); err == nil {
+			err = closeErr
+		}
+		if err == nil {
+			err = moveFile(path, trashed.FilesPath)
+		}
+		if err != nil {
+			os.Remove(trashed.InfoPath)
+			return TrashedFile{}, err
+		}
+		return trashed, nil
+	}
+}
+
+// Restore moves the file back to its original location and removes its .trashinfo file.
+// It refuses to overwrite a file created at the original location in the meantime.
+func (f TrashedFile) Restore() error {
+	if _, err := os.Lstat(f.OriginalPath); err == nil {
+		return fmt.Errorf("cannot restore %s: a file with that name exists", f.OriginalPath)
+	}
+	if err := moveFile(f.FilesPath, f.OriginalPath); err != nil {
+		return err
+	}
+	return os.Remove(f.InfoPath)
+}
+
+// MoveFileToTrash moves the specified file to the trash of the current user.
+func MoveFileToTrash(path string) (TrashedFile, error) {
+	trash, err := HomeTrash()
+	if err != nil {
+		return TrashedFile{}, err
+	}
+	return trash.MoveToTrash(path)
+}
@@ -0,0 +1,141 @@
+package util
+
+import (
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMoveToTrashWritesTrashInfo(t *testing.T) {
+	trash := NewTrash(t.TempDir())
+	path := filepath.Join(t.TempDir(), "a b%.png")
+	if err := os.WriteFile(path, []byte("image"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	before := time.Now().Truncate(time.Second)
+	trashed, err := trash.MoveToTrash(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if FileExists(path) {
+		t.Error("file still at its original path")
+	}
+	if data, err := os.ReadFile(trashed.FilesPath); err != nil || string(data) != "image" {
+		t.Errorf("trashed file: %q, %v", data, err)
+	}
+	if trashed.InfoPath != filepath.Join(trash.homeTrash, "info", "a b%.png.trashinfo") {
+		t.Errorf("info file at %s", trashed.InfoPath)
+	}
+
+	data, err := os.ReadFile(trashed.InfoPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSuffix(string(data), "\n"), "\n")
+	if len(lines) != 3 || lines[0] != "[Trash Info]" {
+		t.Fatalf("info file:\n%s", data)
+	}
+	escaped, ok := strings.CutPrefix(lines[1], "Path=")
+	if !ok || !strings.HasSuffix(escaped, "/a%20b%25.png") {
+		t.Errorf("path line %q is not URL-escaped", lines[1])
+	}
+	if unescaped, err := url.PathUnescape(escaped); err != nil || unescaped != path {
+		t.Errorf("path %q unescapes to %q, want %q", escaped, unescaped, path)
+	}
+	date, ok := strings.CutPrefix(lines[2], "DeletionDate=")
+	if !ok {
+		t.Fatalf("missing deletion date in %q", lines[2])
+	}
+	deleted, err := time.ParseInLocation("2006-01-02T15:04:05", date, time.Local)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if deleted.Before(before) || deleted.After(time.Now()) {
+		t.Errorf("deletion date %v, want between %v and now", deleted, before)
+	}
+}
+
+func TestMoveToTrashRenamesCollisions(t *testing.T) {
+	trash := NewTrash(t.TempDir())
+	var trashed []TrashedFile
+	for _, content := range []string{"first", "second", "third"} {
+		path := filepath.Join(t.TempDir(), "photo.jpg")
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		file, err := trash.MoveToTrash(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		trashed = append(trashed, file)
+	}
+
+	for i, name := range []string{"photo.jpg", "photo.2.jpg", "photo.3.jpg"} {
+		if filepath.Base(trashed[i].FilesPath) != name || filepath.Base(trashed[i].InfoPath) != name+".trashinfo" {
+			t.Errorf("file %d trashed as %s with %s, want %s", i, trashed[i].FilesPath, trashed[i].InfoPath, name)
+		}
+	}
+	if data, err := os.ReadFile(trashed[1].FilesPath); err != nil || string(data) != "second" {
+		t.Errorf("second file: %q, %v", data, err)
+	}
+
+	// A file without an info file, left behind by an interrupted move, blocks its name too
+	orphan := filepath.Join(trash.homeTrash, "files", "photo.4.jpg")
+	if err := os.WriteFile(orphan, nil, 0600); err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "photo.jpg")
+	if err := os.WriteFile(path, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	file, err := trash.MoveToTrash(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if filepath.Base(file.FilesPath) != "photo.5.jpg" {
+		t.Errorf("trashed as %s next to an orphaned photo.4.jpg", file.FilesPath)
+	}
+	if FileExists(filepath.Join(trash.homeTrash, "info", "photo.4.jpg.trashinfo")) {
+		t.Error("info file claimed for the orphaned name was not removed")
+	}
+}
+
+func TestTrashedFileRestore(t *testing.T) {
+	trash := NewTrash(t.TempDir())
+	path := filepath.Join(t.TempDir(), "a.png")
+	if err := os.WriteFile(path, []byte("image"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	trashed, err := trash.MoveToTrash(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A file created at the original location in the meantime is not overwritten
+	if err := os.WriteFile(path, []byte("new"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := trashed.Restore(); err == nil {
+		t.Error("restored over a new file")
+	}
+	if data, _ := os.ReadFile(path); string(data) != "new" {
+		t.Errorf("new file overwritten with %q", data)
+	}
+	if err := os.Remove(path); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := trashed.Restore(); err != nil {
+		t.Fatal(err)
+	}
+	if data, err := os.ReadFile(path); err != nil || string(data) != "image" {
+		t.Errorf("restored file: %q, %v", data, err)
+	}
+	if FileExists(trashed.FilesPath) || FileExists(trashed.InfoPath) {
+		t.Error("file or info file left in the trash")
+	}
+}
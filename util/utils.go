@@ -2,17 +2,17 @@ package util
 
 import (
 	"image"
-	"image/color"
 	"image/draw"
-	"math"
 	"os"
-	"os/exec"
 	"path/filepath"
 	"strconv"
 	"strings"
 
 	"github.com/disintegration/imaging"
 	"github.com/nfnt/resize"
+
+	// Registers the WebP decoder (lossy, lossless and alpha) with image.Decode used by imaging.Open
+	_ "golang.org/x/image/webp"
 )
 
 // ScalingAlgorithm defines the type for image scaling algorithms.
@@ -30,27 +30,6 @@ const (
 	ImageMaxHeight = 400 // Maximum height for the images
 )
 
-// cropImageFast crops the src image horizontally based on ratio (0 to 1).
-// ratio=0 shows right half only, ratio=1 shows left half only.
-// The cropped area moves accordingly.
-func CropImageFast(src *image.Image, ratio float64, algo ScalingAlgorithm) image.Image {
-	bounds := (*src).Bounds()
-	w, h := bounds.Dx(), bounds.Dy()
-
-	// Clamp ratio [0,1]
-	ratio = max(0, ratio)
-	if ratio > 1 {
-		ratio = 1
-	}
-
-	endX := int(float64(w) * ratio)
-	destRect := image.Rect(0, 0, endX, h)
-
-	croppedImage := image.NewRGBA(image.Rect(0, 0, w, h))
-	draw.Draw(croppedImage, destRect, *src, image.Point{}, draw.Src)
-	return RescaleImageFast(croppedImage, algo)
-}
-
 // rescaleImageFast rescales the src image to fit within ImageMaxWidth and ImageMaxHeight using Bilinear interpolation.
 func RescaleImageFast(src image.Image, algo ScalingAlgorithm) image.Image {
 	w, h := GetScaledBounds(&src)
@@ -64,100 +43,88 @@ func RescaleImageFast(src image.Image, algo ScalingAlgorithm) image.Image {
 	return resize.Resize(uint(w), uint(h), src, interp)
 }
 
-// absDiff calculates the absolute difference between two uint32 values.
-func absDiff(a, b uint32) uint32 {
-	if a > b {
-		return a - b
+// ThumbnailImage rescales the src image to fit within a square of the given size, keeping its aspect ratio.
+func ThumbnailImage(src image.Image, size uint, algo ScalingAlgorithm) image.Image {
+	interp := resize.Bilinear
+	if algo == NearestNeighbor {
+		interp = resize.NearestNeighbor
 	}
-	return b - a
+	return resize.Thumbnail(size, size, src, interp)
 }
 
-// computeImageDiff computes the pixel-wise difference between two images.
-// It returns a new image showing the differences and the mean absolute error (MAE).
-func ComputeImageDiffFast(
-	img1, img2 *image.Image,
-	algo ScalingAlgorithm,
-	differenceAsMonochrome bool,
-) (image.Image, float64, uint64) {
-	bounds := (*img1).Bounds()
-	// Computing difference requires both images to have the same bounds.
-	img22 := *img2
-	if !(*img2).Bounds().Eq(bounds) {
-		var filter imaging.ResampleFilter
-		switch algo {
-		case NearestNeighbor:
-			filter = imaging.NearestNeighbor
-		default:
-			filter = imaging.Linear
+// MaxPoolDownscale shrinks src by an integer factor, taking the largest value of each channel
+// over every factor x factor block, so that even a single bright pixel stays visible in the result.
+func MaxPoolDownscale(src image.Image, factor int) *image.NRGBA {
+	in := imaging.Clone(src)
+	factor = max(1, factor)
+	w := (in.Bounds().Dx() + factor - 1) / factor
+	h := (in.Bounds().Dy() + factor - 1) / factor
+	dst := image.NewNRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < in.Bounds().Dy(); y++ {
+		srcRow := in.Pix[y*in.Stride : y*in.Stride+in.Bounds().Dx()*4]
+		dstRow := dst.Pix[(y/factor)*dst.Stride:]
+		for i := 0; i < len(srcRow); i++ {
+			j := (i/4/factor)*4 + i%4
+			dstRow[j] = max(dstRow[j], srcRow[i])
 		}
-		img22 = imaging.Resize(*img2, bounds.Dx(), bounds.Dy(), filter)
 	}
+	return dst
+}
 
-	var amplificationFactor float64 = 5.0
-
-	diff := image.NewRGBA(bounds)
-	var totalDiff uint64
-	var pixelCount uint64
-
-	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
-		for x := bounds.Min.X; x < bounds.Max.X; x++ {
-			r1, g1, b1, _ := (*img1).At(x, y).RGBA()
-			r2, g2, b2, _ := img22.At(x, y).RGBA()
-
-			dr := absDiff(r1, r2) >> 8
-			dg := absDiff(g1, g2) >> 8
-			db := absDiff(b1, b2) >> 8
-
-			ampR := math.Min(float64(dr)*amplificationFactor, 255)
-			ampG := math.Min(float64(dg)*amplificationFactor, 255)
-			ampB := math.Min(float64(db)*amplificationFactor, 255)
-
-			// If monochrome, show the same red color for all differences
-			if differenceAsMonochrome {
-				// If there is no difference, set pixel to black
-				if dr == 0 && dg == 0 && db == 0 {
-					ampR, ampG, ampB = 0, 0, 0
-				} else {
-					// Otherwise, set everything to red channel for visibility
-					ampG = 0
-					ampB = 0
-					ampR = 240
-				}
-			}
-
-			diff.Set(x, y, color.RGBA{
-				R: uint8(ampR),
-				G: uint8(ampG),
-				B: uint8(ampB),
-				A: 255,
-			})
-
-			totalDiff += uint64(dr) + uint64(dg) + uint64(db)
-			pixelCount++
-		}
-	}
+// CropAround copies the size x size square of src centered on the pixel (x, y), relative to the bounds of src.
+// Parts of the square outside of src are left transparent, so the center pixel always stays in the middle.
+func CropAround(src image.Image, x, y, size int) *image.NRGBA {
+	bounds := src.Bounds()
+	dst := image.NewNRGBA(image.Rect(0, 0, size, size))
+	origin := image.Pt(bounds.Min.X+x-size/2, bounds.Min.Y+y-size/2)
+	draw.Draw(dst, dst.Bounds(), src, origin, draw.Src)
+	return dst
+}
 
-	mae := float64(totalDiff) / float64(pixelCount*3)
-	return diff, mae, pixelCount
+// absDiff calculates the absolute difference between two uint32 values.
+func absDiff(a, b uint32) uint32 {
+	if a > b {
+		return a - b
+	}
+	return b - a
 }
 
 // loadImage attempts to load an image from the given path.
-// It includes special handling for .jxl files, converting them to PNG using 'djxl' utility.
-// TODO: handle webps and animated versions of those formats
+// JPEG XL files are decoded by the external JXLDecoderCommand.
+// WebP files are decoded natively.
+// Only the first frame of animated images is returned, use LoadAnimation for all frames.
+// If ApplyOrientation is set, the image is rotated according to its EXIF orientation tag.
 func LoadImage(path string) (image.Image, error) {
 	ext := filepath.Ext(path)
-	if ext == ".jxl" {
-		// Convert JXL to PNG using djxl. This assumes 'djxl' is available in the system's PATH.
-		tmpFile := path + ".converted.jpg"
-		cmd := exec.Command("djxl", path, tmpFile)
-		err := cmd.Run()
-		if err != nil {
-			return nil, err
-		}
-		defer os.Remove(tmpFile)
-		return imaging.Open(tmpFile)
+	if strings.EqualFold(ext, ".jxl") {
+		return loadJXL(path)
+	}
+	img, err := imaging.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	if ApplyOrientation {
+		img = orientImage(img, ReadOrientation(path))
 	}
-	return imaging.Open(path)
+	return img, nil
+}
+
+// ImageFormat returns the name of the format of an image file, such as "jpeg" or "webp".
+// Formats that cannot be decoded natively are named after the file extension.
+func ImageFormat(path string) string {
+	file, err := os.Open(path)
+	if err != nil {
+		return "unknown"
+	}
+	defer file.Close()
+
+	if _, format, err := image.DecodeConfig(file); err == nil {
+		return format
+	}
+	if ext := strings.TrimPrefix(strings.ToLower(filepath.Ext(path)), "."); ext != "" {
+		return ext
+	}
+	return "unknown"
 }
 
 // formatIntWithSpaces takes an integer and returns a string
@@ -201,10 +168,3 @@ func GetScaledBounds(srcImage *image.Image) (float32, float32) {
 
 	return sizeX, sizeY
 }
-
-// MoveFileToTrash moves the specified file to the system trash/recycle bin.
-// It uses `trash` command underneath, which should be available on most systems.
-func MoveFileToTrash(path string) error {
-	cmd := exec.Command("trash", path)
-	return cmd.Run()
-}
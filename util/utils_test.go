@@ -0,0 +1,71 @@
+package util
+
+import (
+	"image"
+	"image/color"
+	"testing"
+)
+
+func TestLoadImageDecodesWebP(t *testing.T) {
+	tests := []struct {
+		file      string
+		size      image.Point
+		pixels    map[image.Point]color.NRGBA
+		tolerance uint8 // Lossy decoders may round differently
+	}{
+		{
+			file: "testdata/lossy.webp",
+			size: image.Pt(150, 100),
+			pixels: map[image.Point]color.NRGBA{
+				{0, 0}:   {29, 32, 29, 255},
+				{75, 50}: {168, 154, 164, 255},
+			},
+			tolerance: 2,
+		},
+		{
+			file: "testdata/lossless.webp",
+			size: image.Pt(75, 100),
+			pixels: map[image.Point]color.NRGBA{
+				{0, 0}:   {255, 255, 255, 255},
+				{18, 25}: {0, 0, 0, 255},
+			},
+		},
+		{
+			file: "testdata/alpha.webp",
+			size: image.Pt(400, 301),
+			pixels: map[image.Point]color.NRGBA{
+				{0, 0}:     {0, 0, 0, 0},
+				{200, 150}: {149, 81, 16, 255},
+				{100, 75}:  {224, 140, 44, 255},
+			},
+			tolerance: 2,
+		},
+	}
+
+	for _, test := range tests {
+		img, err := LoadImage(test.file)
+		if err != nil {
+			t.Errorf("%s: %v", test.file, err)
+			continue
+		}
+		if format := ImageFormat(test.file); format != "webp" {
+			t.Errorf("%s: format %q, want webp", test.file, format)
+		}
+		if size := img.Bounds().Size(); size != test.size {
+			t.Errorf("%s: size %v, want %v", test.file, size, test.size)
+		}
+		for p, want := range test.pixels {
+			got := color.NRGBAModel.Convert(img.At(img.Bounds().Min.X+p.X, img.Bounds().Min.Y+p.Y)).(color.NRGBA)
+			if !colorsClose(got, want, test.tolerance) {
+				t.Errorf("%s: pixel %v is %v, want %v", test.file, p, got, want)
+			}
+		}
+	}
+}
+
+func colorsClose(a, b color.NRGBA, tolerance uint8) bool {
+	close := func(x, y uint8) bool {
+		return max(x, y)-min(x, y) <= tolerance
+	}
+	return close(a.R, b.R) && close(a.G, b.G) && close(a.B, b.B) && close(a.A, b.A)
+}
@@ -0,0 +1,169 @@
+package util
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"image"
+	"time"
+
+	"golang.org/x/image/webp"
+)
+
+// VP8X feature flags.
+const (
+	webpFlagAnimation = 0x02
+	webpFlagAlpha     = 0x10
+)
+
+// ANMF frame flags.
+const (
+	webpFrameDispose = 0x01
+	webpFrameNoBlend = 0x02
+)
+
+// riffChunk is a raw RIFF chunk without its header and padding.
+type riffChunk struct {
+	FourCC string
+	Data   []byte
+}
+
+// readRIFFChunks splits the payload of a RIFF container into chunks.
+func readRIFFChunks(data []byte) ([]riffChunk, error) {
+	var chunks []riffChunk
+	for len(data) >= 8 {
+		size := binary.LittleEndian.Uint32(data[4:8])
+		if uint64(size)+8 > uint64(len(data)) {
+			return nil, errors.New("truncated RIFF chunk")
+		}
+		chunks = append(chunks, riffChunk{
+			FourCC: string(data[0:4]),
+			Data:   data[8 : 8+size],
+		})
+		// Chunks are padded to an even size
+		next := 8 + int(size) + int(size&1)
+		data = data[min(next, len(data)):]
+	}
+	return chunks, nil
+}
+
+// writeRIFFChunk appends a chunk with its header and padding to the buffer.
+func writeRIFFChunk(buf *bytes.Buffer, fourCC string, data []byte) {
+	var header [8]byte
+	copy(header[0:4], fourCC)
+	binary.LittleEndian.PutUint32(header[4:8], uint32(len(data)))
+	buf.Write(header[:])
+	buf.Write(data)
+	if len(data)&1 == 1 {
+		buf.WriteByte(0)
+	}
+}
+
+// readUint24 reads a little-endian 24-bit integer.
+func readUint24(b []byte) int {
+	return int(b[0]) | int(b[1])<<8 | int(b[2])<<16
+}
+
+// putUint24 writes a little-endian 24-bit integer.
+func putUint24(b []byte, v int) {
+	b[0] = byte(v)
+	b[1] = byte(v >> 8)
+	b[2] = byte(v >> 16)
+}
+
+// decodeWebPAnimation composites all frames of an animated WebP. It returns nil for still WebP files.
+// Each frame is decoded by repackaging its bitstream as a standalone WebP file.
+func decodeWebPAnimation(data []byte) (*Animation, error) {
+	chunks, err := readRIFFChunks(data[12:])
+	if err != nil {
+		return nil, err
+	}
+	if len(chunks) == 0 || chunks[0].FourCC != "VP8X" || len(chunks[0].Data) < 10 {
+		return nil, nil
+	}
+	vp8x := chunks[0].Data
+	if vp8x[0]&webpFlagAnimation == 0 {
+		return nil, nil
+	}
+
+	canvasWidth := readUint24(vp8x[4:7]) + 1
+	canvasHeight := readUint24(vp8x[7:10]) + 1
+	if err := checkCanvasSize(canvasWidth, canvasHeight); err != nil {
+		return nil, err
+	}
+	canvas := image.NewRGBA(image.Rect(0, 0, canvasWidth, canvasHeight))
+
+	anim := &Animation{}
+	for _, chunk := range chunks[1:] {
+		if chunk.FourCC != "ANMF" {
+			continue
+		}
+		if len(chunk.Data) < 16 {
+			return nil, errors.New("invalid ANMF chunk")
+		}
+
+		x := readUint24(chunk.Data[0:3]) * 2
+		y := readUint24(chunk.Data[3:6]) * 2
+		width := readUint24(chunk.Data[6:9]) + 1
+		height := readUint24(chunk.Data[9:12]) + 1
+		delay := time.Duration(readUint24(chunk.Data[12:15])) * time.Millisecond
+		if delay <= 0 {
+			delay = defaultFrameDelay
+		}
+		flags := chunk.Data[15]
+		rect := image.Rect(x, y, x+width, y+height)
+
+		frameImage, err := decodeWebPFrame(chunk.Data[16:], width, height)
+		if err != nil {
+			return nil, err
+		}
+
+		compositeFrame(canvas, frameImage, rect, flags&webpFrameNoBlend == 0)
+		anim.Frames = append(anim.Frames, Frame{Image: cloneRGBA(canvas), Delay: delay})
+
+		if flags&webpFrameDispose != 0 {
+			clearRect(canvas, rect)
+		}
+	}
+	if len(anim.Frames) < 2 {
+		return nil, nil
+	}
+	return anim, nil
+}
+
+// decodeWebPFrame wraps the ALPH and VP8/VP8L chunks of a frame into a standalone WebP file and decodes it.
+func decodeWebPFrame(frameData []byte, width, height int) (image.Image, error) {
+	chunks, err := readRIFFChunks(frameData)
+	if err != nil {
+		return nil, err
+	}
+
+	var body bytes.Buffer
+	for _, chunk := range chunks {
+		if chunk.FourCC == "ALPH" {
+			// Lossy frames with alpha need an extended header describing the frame size
+			vp8x := make([]byte, 10)
+			vp8x[0] = webpFlagAlpha
+			putUint24(vp8x[4:7], width-1)
+			putUint24(vp8x[7:10], height-1)
+			writeRIFFChunk(&body, "VP8X", vp8x)
+			break
+		}
+	}
+	for _, chunk := range chunks {
+		switch chunk.FourCC {
+		case "ALPH", "VP8 ", "VP8L":
+			writeRIFFChunk(&body, chunk.FourCC, chunk.Data)
+		}
+	}
+
+	var file bytes.Buffer
+	file.WriteString("RIFF")
+	var size [4]byte
+	binary.LittleEndian.PutUint32(size[:], uint32(4+body.Len()))
+	file.Write(size[:])
+	file.WriteString("WEBP")
+	file.Write(body.Bytes())
+
+	return webp.Decode(&file)
+}
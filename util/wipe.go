@@ -0,0 +1,53 @@
+package util
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+)
+
+// WipeImage copies the part of src before position (0 to 1) along the x axis, or the y axis if vertical,
+// leaving the rest transparent so that the image below shows through.
+func WipeImage(src image.Image, position float64, vertical bool) *image.NRGBA {
+	bounds := src.Bounds()
+	position = max(0, min(1, position))
+	dst := image.NewNRGBA(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+	rect := image.Rect(0, 0, int(float64(bounds.Dx())*position), bounds.Dy())
+	if vertical {
+		rect = image.Rect(0, 0, bounds.Dx(), int(float64(bounds.Dy())*position))
+	}
+	draw.Draw(dst, rect, src, bounds.Min, draw.Src)
+	return dst
+}
+
+// SpotlightImage copies the circle of src with the given center and radius, relative to the bounds of src,
+// leaving the rest transparent.
+func SpotlightImage(src image.Image, center image.Point, radius int) *image.NRGBA {
+	bounds := src.Bounds()
+	dst := image.NewNRGBA(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+	mask := circleMask{center: center, radius: radius}
+	draw.DrawMask(dst, dst.Bounds(), src, bounds.Min, mask, image.Point{}, draw.Src)
+	return dst
+}
+
+// circleMask is opaque inside a circle and transparent outside of it.
+type circleMask struct {
+	center image.Point
+	radius int
+}
+
+func (m circleMask) ColorModel() color.Model {
+	return color.AlphaModel
+}
+
+func (m circleMask) Bounds() image.Rectangle {
+	return image.Rect(m.center.X-m.radius, m.center.Y-m.radius, m.center.X+m.radius+1, m.center.Y+m.radius+1)
+}
+
+func (m circleMask) At(x, y int) color.Color {
+	dx, dy := x-m.center.X, y-m.center.Y
+	if dx*dx+dy*dy <= m.radius*m.radius {
+		return color.Opaque
+	}
+	return color.Transparent
+}